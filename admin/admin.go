@@ -0,0 +1,273 @@
+// Package admin serves an HTTP API to inspect and change the logging of
+// a running program: log levels, level rules, and the handler tree.
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+
+	log "github.com/wuzuoliang/log"
+	"github.com/wuzuoliang/log/ext"
+)
+
+// maxDepth bounds the walk of a handler tree in case it has a cycle.
+const maxDepth = 64
+
+// Handler returns an http.Handler serving the admin API. Mount it under
+// a prefix with http.StripPrefix:
+//
+//     mux.Handle("/debug/log/", http.StripPrefix("/debug/log", admin.Handler()))
+//
+// It serves:
+//
+//     GET  /level           the root level: {"level":"info"}
+//     PUT  /level           set the root level
+//     GET  /loggers         the named loggers: [{"name":"db","level":"debug"}]
+//     GET  /loggers/{name}  one named logger
+//     PUT  /loggers/{name}  set a named logger's level
+//     GET  /rules           the level rules: {"rules":"db/*=trace"}
+//     PUT  /rules           replace the level rules, see log.SetLevelRules
+//     GET  /handlers        the root handler tree, or ?logger={name}'s
+//     POST /rotate          rotate every file of FileHandlerRotate: {"rotated":1}
+//     POST /flush           dump every ext.Speculative buffer: {"flushed":1}
+//
+// PUT bodies are either JSON, such as {"level":"debug"}, or the bare
+// value, so `curl -X PUT -d debug host/debug/log/level` works.
+func Handler() http.Handler {
+	return http.HandlerFunc(serve)
+}
+
+type levelBody struct {
+	Name  string `json:"name,omitempty"`
+	Level string `json:"level"`
+}
+
+type rulesBody struct {
+	Rules string `json:"rules"`
+}
+
+// handlerNode describes one handler of a tree.
+type handlerNode struct {
+	Handler  string         `json:"handler"`
+	Children []*handlerNode `json:"children,omitempty"`
+}
+
+func serve(w http.ResponseWriter, r *http.Request) {
+	p := strings.Trim(r.URL.Path, "/")
+	switch {
+	case p == "level":
+		serveLevel(w, r, "", log.Root())
+	case p == "loggers":
+		if !allow(w, r, http.MethodGet) {
+			return
+		}
+		loggers := []levelBody{}
+		for _, name := range log.LoggerNames() {
+			loggers = append(loggers, levelBody{Name: name, Level: log.Named(name).GetOutLevel().String()})
+		}
+		reply(w, http.StatusOK, loggers)
+	case strings.HasPrefix(p, "loggers/"):
+		name := strings.TrimPrefix(p, "loggers/")
+		// loggers are made by the program, or by rules for those to come
+		if !exists(name) {
+			fail(w, http.StatusNotFound, fmt.Errorf("no logger named %q", name))
+			return
+		}
+		serveLevel(w, r, name, log.Named(name))
+	case p == "rules":
+		serveRules(w, r)
+	case p == "handlers":
+		if !allow(w, r, http.MethodGet) {
+			return
+		}
+		l := log.Root()
+		if name := r.URL.Query().Get("logger"); name != "" {
+			if !exists(name) {
+				fail(w, http.StatusNotFound, fmt.Errorf("no logger named %q", name))
+				return
+			}
+			l = log.Named(name)
+		}
+		reply(w, http.StatusOK, describe(l.GetHandler(), 0))
+	case p == "rotate":
+		if !allow(w, r, http.MethodPost) {
+			return
+		}
+		n, err := each(func(h log.Handler) (bool, error) {
+			rh, ok := h.(interface{ Rotate() error })
+			if !ok {
+				return false, nil
+			}
+			return true, rh.Rotate()
+		})
+		if err != nil {
+			fail(w, http.StatusInternalServerError, err)
+			return
+		}
+		reply(w, http.StatusOK, map[string]int{"rotated": n})
+	case p == "flush":
+		if !allow(w, r, http.MethodPost) {
+			return
+		}
+		n, err := each(func(h log.Handler) (bool, error) {
+			s, ok := h.(*ext.Speculative)
+			if !ok {
+				return false, nil
+			}
+			return true, s.Dump()
+		})
+		if err != nil {
+			fail(w, http.StatusInternalServerError, err)
+			return
+		}
+		reply(w, http.StatusOK, map[string]int{"flushed": n})
+	default:
+		fail(w, http.StatusNotFound, fmt.Errorf("unknown path %q", r.URL.Path))
+	}
+}
+
+func serveLevel(w http.ResponseWriter, r *http.Request, name string, l log.Logger) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var body levelBody
+		if err := readBody(w, r, &body, &body.Level); err != nil {
+			fail(w, http.StatusBadRequest, err)
+			return
+		}
+		level, err := log.ParseLevel(body.Level)
+		if err != nil {
+			fail(w, http.StatusBadRequest, err)
+			return
+		}
+		l.SetOutLevel(level)
+	default:
+		allow(w, r, http.MethodGet, http.MethodPut)
+		return
+	}
+	reply(w, http.StatusOK, levelBody{Name: name, Level: l.GetOutLevel().String()})
+}
+
+func serveRules(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var body rulesBody
+		if err := readBody(w, r, &body, &body.Rules); err != nil {
+			fail(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := log.SetLevelRules(body.Rules); err != nil {
+			fail(w, http.StatusBadRequest, err)
+			return
+		}
+	default:
+		allow(w, r, http.MethodGet, http.MethodPut)
+		return
+	}
+	reply(w, http.StatusOK, rulesBody{Rules: log.LevelRules()})
+}
+
+// readBody decodes a JSON request body into v, or stores a body which
+// isn't JSON in bare.
+func readBody(w http.ResponseWriter, r *http.Request, v interface{}, bare *string) error {
+	b, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	s := strings.TrimSpace(string(b))
+	if strings.HasPrefix(s, "{") {
+		return json.Unmarshal(b, v)
+	}
+	*bare = s
+	return nil
+}
+
+func exists(name string) bool {
+	for _, n := range log.LoggerNames() {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func describe(h log.Handler, depth int) *handlerNode {
+	node := &handlerNode{Handler: handlerName(h)}
+	if depth >= maxDepth {
+		return node
+	}
+	for _, child := range log.UnwrapHandler(h) {
+		node.Children = append(node.Children, describe(child, depth+1))
+	}
+	return node
+}
+
+func handlerName(h log.Handler) string {
+	if s, ok := h.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", h)
+}
+
+// each calls fn once for every distinct handler reachable from the root
+// and the named loggers, counting the handlers fn acted on. It returns
+// the first error fn returns.
+func each(fn func(h log.Handler) (bool, error)) (n int, err error) {
+	seen := map[log.Handler]bool{}
+	var walk func(h log.Handler, depth int)
+	walk = func(h log.Handler, depth int) {
+		if h == nil || depth > maxDepth {
+			return
+		}
+		// only pointers are safe to use as map keys; other handlers,
+		// such as FuncHandler's, are visited every time they are reached
+		if reflect.ValueOf(h).Kind() == reflect.Ptr {
+			if seen[h] {
+				return
+			}
+			seen[h] = true
+		}
+		acted, errFn := fn(h)
+		if acted {
+			n++
+		}
+		if err == nil && errFn != nil {
+			err = errFn
+		}
+		for _, child := range log.UnwrapHandler(h) {
+			walk(child, depth+1)
+		}
+	}
+
+	walk(log.Root().GetHandler(), 0)
+	for _, name := range log.LoggerNames() {
+		walk(log.Named(name).GetHandler(), 0)
+	}
+	return n, err
+}
+
+func allow(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	fail(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	return false
+}
+
+func reply(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func fail(w http.ResponseWriter, code int, err error) {
+	reply(w, code, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,131 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	log "github.com/wuzuoliang/log"
+	"github.com/wuzuoliang/log/ext"
+)
+
+func do(t *testing.T, method, path, body string, v interface{}) int {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, req)
+	if v != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s %s: bad reply %q: %v", method, path, rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+func TestLevels(t *testing.T) {
+	defer log.SetOutLevel(log.LvlTrace)
+
+	var lvl levelBody
+	if code := do(t, "PUT", "/level", "info", &lvl); code != http.StatusOK || lvl.Level != "info" {
+		t.Fatalf("PUT /level: %d %+v", code, lvl)
+	}
+	if log.GetLogLevel() != log.LvlInfo {
+		t.Fatalf("root level not changed: %v", log.GetLogLevel())
+	}
+	if code := do(t, "PUT", "/level", "loud", nil); code != http.StatusBadRequest {
+		t.Fatalf("PUT bad level: %d", code)
+	}
+
+	if code := do(t, "GET", "/loggers/admin.missing", "", nil); code != http.StatusNotFound {
+		t.Fatalf("GET unknown logger: %d", code)
+	}
+	if code := do(t, "PUT", "/loggers/admin.missing", `{"level":"debug"}`, nil); code != http.StatusNotFound {
+		t.Fatalf("PUT unknown logger: %d", code)
+	}
+	for _, name := range log.LoggerNames() {
+		if name == "admin.missing" {
+			t.Fatalf("PUT created logger %q", name)
+		}
+	}
+
+	log.Named("admin.test")
+	if code := do(t, "PUT", "/loggers/admin.test", `{"level":"debug"}`, &lvl); code != http.StatusOK || lvl.Level != "debug" {
+		t.Fatalf("PUT logger: %d %+v", code, lvl)
+	}
+	var loggers []levelBody
+	do(t, "GET", "/loggers", "", &loggers)
+	got := map[string]string{}
+	for _, l := range loggers {
+		got[l.Name] = l.Level
+	}
+	if got["admin"] != "info" || got["admin.test"] != "debug" {
+		t.Fatalf("GET /loggers: %+v", loggers)
+	}
+
+	var rules rulesBody
+	if code := do(t, "PUT", "/rules", "admin.*=warn", &rules); code != http.StatusOK || rules.Rules != "admin.*=warn" {
+		t.Fatalf("PUT /rules: %d %+v", code, rules)
+	}
+	defer log.SetLevelRules("")
+	if code := do(t, "PUT", "/rules", "admin", nil); code != http.StatusBadRequest {
+		t.Fatalf("PUT bad rules: %d", code)
+	}
+
+	if code := do(t, "DELETE", "/level", "", nil); code != http.StatusMethodNotAllowed {
+		t.Fatalf("DELETE /level: %d", code)
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log-admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file, err := log.FileHandlerRotate(filepath.Join(dir, "app.log"), log.LogfmtFormat(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.CloseHandler(file)
+	var buf bytes.Buffer
+	spec := ext.SpeculativeHandler(10, log.StreamHandler(&buf, log.LogfmtFormat()))
+
+	old := log.Root().GetHandler()
+	defer log.Root().SetHandler(old)
+	log.Root().SetHandler(log.LvlFilterHandler(log.LvlInfo, log.MultiHandler(file, spec)))
+
+	var tree handlerNode
+	if code := do(t, "GET", "/handlers", "", &tree); code != http.StatusOK {
+		t.Fatalf("GET /handlers: %d", code)
+	}
+	if tree.Handler != "log.FilterHandler" || len(tree.Children) != 1 ||
+		tree.Children[0].Handler != "log.MultiHandler" || len(tree.Children[0].Children) != 2 ||
+		tree.Children[0].Children[1].Handler != "*ext.Speculative" {
+		b, _ := json.Marshal(tree)
+		t.Fatalf("unexpected tree %s", b)
+	}
+
+	log.Info("speculative")
+	var n map[string]int
+	if code := do(t, "POST", "/flush", "", &n); code != http.StatusOK || n["flushed"] != 1 {
+		t.Fatalf("POST /flush: %d %v", code, n)
+	}
+	if !strings.Contains(buf.String(), "speculative") {
+		t.Fatalf("speculative buffer not flushed: %q", buf.String())
+	}
+
+	if code := do(t, "POST", "/rotate", "", &n); code != http.StatusOK || n["rotated"] != 1 {
+		t.Fatalf("POST /rotate: %d %v", code, n)
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 2 {
+		t.Fatalf("expected a backup after rotating, got %d files", len(files))
+	}
+}
@@ -0,0 +1,348 @@
+package log
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-stack/stack"
+)
+
+// OverflowPolicy decides what an Async handler does with a record
+// when its queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks the caller until there is room in the queue.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest discards the record being logged.
+	OverflowDropNewest
+	// OverflowDropOldest discards the oldest queued record to make room.
+	OverflowDropOldest
+	// OverflowDropBelowLevel discards the record being logged if it is
+	// less severe than the drop level, and blocks otherwise.
+	OverflowDropBelowLevel
+)
+
+// asyncOptions storage async handler parameters
+type asyncOptions struct {
+	queueSize      int            // 队列长度
+	overflow       OverflowPolicy // 队列满时的处理策略
+	dropLevel      Level          // OverflowDropBelowLevel 时保留的最低级别
+	batchSize      int            // 每批写入的最大记录数
+	flushInterval  time.Duration  // 定时 Flush 被包装的 handler
+	reportInterval time.Duration  // 定时输出丢弃/失败统计
+}
+
+var _defaultAsyncOptions = asyncOptions{
+	queueSize:      1024,
+	overflow:       OverflowBlock,
+	dropLevel:      LvlWarn,
+	batchSize:      64,
+	flushInterval:  time.Second,
+	reportInterval: time.Minute,
+}
+
+type AsyncOptions func(*asyncOptions)
+
+// SetAsyncQueueSize sets how many records may wait for the wrapped handler.
+// With zero, Log waits for the background goroutine to take each record;
+// the drop policies keep a queue of at least one.
+func SetAsyncQueueSize(size int) AsyncOptions {
+	return func(o *asyncOptions) {
+		if size >= 0 {
+			o.queueSize = size
+		}
+	}
+}
+
+// SetAsyncOverflow sets what happens to records logged while the queue is full.
+func SetAsyncOverflow(policy OverflowPolicy) AsyncOptions {
+	return func(o *asyncOptions) {
+		o.overflow = policy
+	}
+}
+
+// SetAsyncDropLevel sets the least severe level that OverflowDropBelowLevel
+// still waits for; anything less severe is dropped when the queue is full.
+func SetAsyncDropLevel(level Level) AsyncOptions {
+	return func(o *asyncOptions) {
+		o.dropLevel = level
+	}
+}
+
+// SetAsyncBatchSize sets how many queued records are written to the
+// wrapped handler in one go before the queue is checked for control
+// requests again.
+func SetAsyncBatchSize(size int) AsyncOptions {
+	return func(o *asyncOptions) {
+		if size > 0 {
+			o.batchSize = size
+		}
+	}
+}
+
+// SetAsyncFlushInterval sets how often the wrapped handler is flushed
+// while records are being written. Zero disables periodic flushing.
+func SetAsyncFlushInterval(d time.Duration) AsyncOptions {
+	return func(o *asyncOptions) {
+		o.flushInterval = d
+	}
+}
+
+// SetAsyncReportInterval sets how often dropped and failed record counts
+// are logged to the wrapped handler. Zero disables the report.
+func SetAsyncReportInterval(d time.Duration) AsyncOptions {
+	return func(o *asyncOptions) {
+		o.reportInterval = d
+	}
+}
+
+// AsyncStats counts what happened to the records given to an Async handler.
+type AsyncStats struct {
+	Queued  int    // records currently waiting in the queue
+	Dropped uint64 // records discarded by the overflow policy
+	Failed  uint64 // records or flushes the wrapped handler returned an error for
+}
+
+// Async is a Handler which writes records to the wrapped handler from
+// a background goroutine. See AsyncHandler.
+type Async struct {
+	opts asyncOptions
+	h    Handler
+
+	mu      sync.RWMutex
+	closed  bool
+	queue   chan *Record
+	flushCh chan chan error
+	done    chan struct{}
+
+	dropped uint64
+	failed  uint64
+}
+
+// AsyncHandler returns a handler that queues records and writes them to
+// h from a background goroutine, so the caller never waits on slow
+// outputs such as NetHandler. What happens when the queue is full is
+// chosen with SetAsyncOverflow; records dropped that way and records h
+// fails to write are counted, and the counts are logged to h every
+// report interval at LvlWarn.
+//
+// Flush waits until every record queued so far has been written and h
+// has been flushed. Close stops accepting records, drains the queue and
+// closes h.
+func AsyncHandler(h Handler, opts ...AsyncOptions) *Async {
+	o := _defaultAsyncOptions
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(&o)
+	}
+	if o.queueSize == 0 && o.overflow != OverflowBlock {
+		// without room for a record the drop policies would drop
+		// nearly everything, or spin
+		o.queueSize = 1
+	}
+
+	a := &Async{
+		opts:    o,
+		h:       h,
+		queue:   make(chan *Record, o.queueSize),
+		flushCh: make(chan chan error),
+		done:    make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+func (a *Async) Log(r *Record) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		return ErrHandlerClosed
+	}
+
+	// the queue outlives this call, so it holds a clone which is
+	// released once written or dropped
+	r = r.Clone()
+	switch a.opts.overflow {
+	case OverflowDropNewest:
+		select {
+		case a.queue <- r:
+		default:
+			atomic.AddUint64(&a.dropped, 1)
+			releaseRecord(r)
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case a.queue <- r:
+				return nil
+			default:
+			}
+			select {
+			case old := <-a.queue:
+				atomic.AddUint64(&a.dropped, 1)
+				releaseRecord(old)
+			default:
+			}
+		}
+	case OverflowDropBelowLevel:
+		if r.Level.Compare(a.opts.dropLevel) <= 0 {
+			a.queue <- r
+			return nil
+		}
+		select {
+		case a.queue <- r:
+		default:
+			atomic.AddUint64(&a.dropped, 1)
+			releaseRecord(r)
+		}
+	default:
+		a.queue <- r
+	}
+	return nil
+}
+
+func (a *Async) Unwrap() []Handler {
+	return []Handler{a.h}
+}
+
+// Stats returns the current queue length and drop/failure counters.
+func (a *Async) Stats() AsyncStats {
+	return AsyncStats{
+		Queued:  len(a.queue),
+		Dropped: atomic.LoadUint64(&a.dropped),
+		Failed:  atomic.LoadUint64(&a.failed),
+	}
+}
+
+func (a *Async) Flush() error {
+	a.mu.RLock()
+	if a.closed {
+		a.mu.RUnlock()
+		return nil
+	}
+	flushed := make(chan error, 1)
+	a.flushCh <- flushed
+	a.mu.RUnlock()
+
+	return <-flushed
+}
+
+func (a *Async) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	close(a.queue)
+	a.mu.Unlock()
+
+	<-a.done
+	return CloseHandler(a.h)
+}
+
+func (a *Async) run() {
+	defer close(a.done)
+
+	var flushC, reportC <-chan time.Time
+	if a.opts.flushInterval > 0 {
+		t := time.NewTicker(a.opts.flushInterval)
+		defer t.Stop()
+		flushC = t.C
+	}
+	if a.opts.reportInterval > 0 {
+		t := time.NewTicker(a.opts.reportInterval)
+		defer t.Stop()
+		reportC = t.C
+	}
+
+	var (
+		dirty    bool
+		reported AsyncStats
+	)
+	for {
+		select {
+		case r, ok := <-a.queue:
+			if !ok {
+				a.flush()
+				a.report(&reported)
+				return
+			}
+			a.write(r)
+			for i := 1; i < a.opts.batchSize; i++ {
+				select {
+				case r, ok := <-a.queue:
+					if !ok {
+						// closed; the outer loop will see it again
+						i = a.opts.batchSize
+						continue
+					}
+					a.write(r)
+				default:
+					i = a.opts.batchSize
+				}
+			}
+			dirty = true
+		case flushed := <-a.flushCh:
+			// write what was queued before the flush request; anything
+			// logged later isn't the caller's concern
+			for n := len(a.queue); n > 0; n-- {
+				r, ok := <-a.queue
+				if !ok {
+					break
+				}
+				a.write(r)
+			}
+			flushed <- a.flush()
+			dirty = false
+		case <-flushC:
+			if dirty {
+				a.flush()
+				dirty = false
+			}
+		case <-reportC:
+			a.report(&reported)
+		}
+	}
+}
+
+func (a *Async) write(r *Record) {
+	if err := a.h.Log(r); err != nil {
+		atomic.AddUint64(&a.failed, 1)
+	}
+	releaseRecord(r)
+}
+
+func (a *Async) flush() error {
+	err := FlushHandler(a.h)
+	if err != nil {
+		atomic.AddUint64(&a.failed, 1)
+	}
+	return err
+}
+
+// report logs the counters that changed since the last report.
+func (a *Async) report(last *AsyncStats) {
+	if a.opts.reportInterval <= 0 {
+		return
+	}
+	cur := a.Stats()
+	dropped, failed := cur.Dropped-last.Dropped, cur.Failed-last.Failed
+	if dropped == 0 && failed == 0 {
+		return
+	}
+	*last = cur
+
+	_ = a.h.Log(&Record{
+		Time:      time.Now(),
+		Level:     LvlWarn,
+		Msg:       "async handler lost records",
+		KeyValues: []interface{}{"dropped", dropped, "failed", failed, "queued", cur.Queued},
+		Call:      stack.Caller(0),
+		KeyNames:  defaultRecordKeyNames,
+	})
+}
@@ -0,0 +1,176 @@
+package log
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gateHandler blocks every Log call until the gate is opened.
+type gateHandler struct {
+	gate chan struct{}
+	mu   sync.Mutex
+	msgs []string
+	fail bool
+}
+
+func newGateHandler() *gateHandler {
+	return &gateHandler{gate: make(chan struct{})}
+}
+
+func (h *gateHandler) Log(r *Record) error {
+	<-h.gate
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.msgs = append(h.msgs, r.Msg)
+	if h.fail {
+		return errors.New("fail")
+	}
+	return nil
+}
+
+func (h *gateHandler) got() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.msgs...)
+}
+
+// fillAsync logs the first record, waits for the consumer to pick it up
+// and block on the gate, then fills the queue.
+func fillAsync(t *testing.T, a *Async, msgs ...string) {
+	a.Log(&Record{Msg: msgs[0], Level: LvlInfo})
+	for i := 0; a.Stats().Queued != 0; i++ {
+		if i > 1000 {
+			t.Fatalf("consumer never picked up the first record")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for _, m := range msgs[1:] {
+		a.Log(&Record{Msg: m, Level: LvlInfo})
+	}
+}
+
+func TestAsyncDropNewest(t *testing.T) {
+	t.Parallel()
+
+	h := newGateHandler()
+	a := AsyncHandler(h, SetAsyncQueueSize(2), SetAsyncOverflow(OverflowDropNewest), SetAsyncReportInterval(0))
+	fillAsync(t, a, "0", "1", "2", "3", "4")
+	close(h.gate)
+	a.Close()
+
+	if got := a.Stats().Dropped; got != 2 {
+		t.Fatalf("got %d dropped records, expected 2", got)
+	}
+	if got := h.got(); len(got) != 3 || got[2] != "2" {
+		t.Fatalf("got %v, expected [0 1 2]", got)
+	}
+}
+
+func TestAsyncDropOldest(t *testing.T) {
+	t.Parallel()
+
+	h := newGateHandler()
+	a := AsyncHandler(h, SetAsyncQueueSize(2), SetAsyncOverflow(OverflowDropOldest), SetAsyncReportInterval(0))
+	fillAsync(t, a, "0", "1", "2", "3", "4")
+	close(h.gate)
+	a.Close()
+
+	if got := a.Stats().Dropped; got != 2 {
+		t.Fatalf("got %d dropped records, expected 2", got)
+	}
+	if got := h.got(); len(got) != 3 || got[1] != "3" || got[2] != "4" {
+		t.Fatalf("got %v, expected [0 3 4]", got)
+	}
+}
+
+func TestAsyncDropOldestNoQueue(t *testing.T) {
+	t.Parallel()
+
+	// a queue of one record rather than none, which would spin
+	h := newGateHandler()
+	a := AsyncHandler(h, SetAsyncQueueSize(0), SetAsyncOverflow(OverflowDropOldest), SetAsyncReportInterval(0))
+	fillAsync(t, a, "0", "1", "2")
+	close(h.gate)
+	a.Close()
+
+	if got := a.Stats().Dropped; got != 1 {
+		t.Fatalf("got %d dropped records, expected 1", got)
+	}
+	if got := h.got(); len(got) != 2 || got[1] != "2" {
+		t.Fatalf("got %v, expected [0 2]", got)
+	}
+}
+
+func TestAsyncDropBelowLevel(t *testing.T) {
+	t.Parallel()
+
+	h := newGateHandler()
+	a := AsyncHandler(h, SetAsyncQueueSize(1), SetAsyncOverflow(OverflowDropBelowLevel),
+		SetAsyncDropLevel(LvlWarn), SetAsyncReportInterval(0))
+	fillAsync(t, a, "0", "1")
+	a.Log(&Record{Msg: "debug", Level: LvlDebug})
+
+	errLogged := make(chan struct{})
+	go func() {
+		a.Log(&Record{Msg: "error", Level: LvlError})
+		close(errLogged)
+	}()
+	close(h.gate)
+	<-errLogged
+	a.Close()
+
+	if got := a.Stats().Dropped; got != 1 {
+		t.Fatalf("got %d dropped records, expected 1", got)
+	}
+	if got := h.got(); len(got) != 3 || got[2] != "error" {
+		t.Fatalf("got %v, expected [0 1 error]", got)
+	}
+}
+
+func TestAsyncFlushAndReport(t *testing.T) {
+	t.Parallel()
+
+	h := newGateHandler()
+	h.fail = true
+	close(h.gate)
+	a := AsyncHandler(h, SetAsyncReportInterval(time.Hour))
+
+	for i := 0; i < 10; i++ {
+		a.Log(&Record{Msg: "msg", Level: LvlInfo})
+	}
+	a.Flush()
+	if got := len(h.got()); got != 10 {
+		t.Fatalf("got %d records after flush, expected 10", got)
+	}
+	if got := a.Stats().Failed; got != 10 {
+		t.Fatalf("got %d failed records, expected 10", got)
+	}
+
+	// the final report is written on close
+	a.Close()
+	got := h.got()
+	if len(got) != 11 || got[10] != "async handler lost records" {
+		t.Fatalf("expected a report record on close, got %v", got)
+	}
+	if err := a.Log(&Record{}); err != ErrHandlerClosed {
+		t.Fatalf("got %v logging after close, expected %v", err, ErrHandlerClosed)
+	}
+}
+
+// flushFailHandler fails every flush.
+type flushFailHandler struct{ Handler }
+
+func (flushFailHandler) Flush() error { return errors.New("flush failed") }
+
+func TestAsyncFlushError(t *testing.T) {
+	t.Parallel()
+
+	a := AsyncHandler(flushFailHandler{DiscardHandler()}, SetAsyncReportInterval(0))
+	defer a.Close()
+	a.Log(&Record{Msg: "msg", Level: LvlInfo})
+	if err := a.Flush(); err == nil || err.Error() != "flush failed" {
+		t.Fatalf("got %v flushing, expected the wrapped handler's error", err)
+	}
+}
@@ -226,3 +226,65 @@ func BenchmarkLog(b *testing.B) {
 		}
 	})
 }
+
+func BenchmarkLooseDisabled(b *testing.B) {
+	logger := newLog15()
+	logger.SetOutLevel(LvlInfo)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Debug("Go fast.", "string", "four!", "int", 1, "duration", time.Second)
+	}
+}
+
+func BenchmarkFieldsDisabled(b *testing.B) {
+	logger := newLog15()
+	logger.SetOutLevel(LvlInfo)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if logger.Enabled(LvlDebug) {
+			logger.LogFields(nil, LvlDebug, "Go fast.", String("string", "four!"), Int("int", 1), Dur("duration", time.Second))
+		}
+	}
+}
+
+func BenchmarkRootFieldsDisabled(b *testing.B) {
+	SetOutLevel(LvlInfo)
+	defer SetOutLevel(LvlTrace)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		LogFields(nil, LvlDebug, "Go fast.", String("string", "four!"), Int("int", 1), Dur("duration", time.Second))
+	}
+}
+
+func BenchmarkLooseDiscard(b *testing.B) {
+	logger := New()
+	logger.SetHandler(DiscardHandler())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info("Go fast.", "string", "four!", "int", 1000, "duration", time.Second)
+	}
+}
+
+func BenchmarkFieldsDiscard(b *testing.B) {
+	logger := New()
+	logger.SetHandler(DiscardHandler())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.LogFields(nil, LvlInfo, "Go fast.", String("string", "four!"), Int("int", 1000), Dur("duration", time.Second))
+	}
+}
+
+func BenchmarkFieldsLogfmt(b *testing.B) {
+	logger := New()
+	logger.SetHandler(StreamHandler(ioutil.Discard, LogfmtFormat()))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.LogFields(nil, LvlInfo, "Go fast.", String("string", "four!"), Int("int", 1000), Dur("duration", time.Second))
+	}
+}
@@ -50,3 +50,23 @@ func (p *bytesBufferPool) Put(x *bytes.Buffer) {
 	}
 	p.pool.Put(x)
 }
+
+// maxPooledBufferSize keeps a single huge record from pinning a large
+// buffer in the pool.
+const maxPooledBufferSize = 256 << 10
+
+// getBuffer returns an empty buffer from the BytesBufferPool.
+func getBuffer() *bytes.Buffer {
+	buf := getBytesBufferPool().Get()
+	buf.Reset()
+	return buf
+}
+
+// putBuffer gives buf back to the BytesBufferPool. buf must not be used
+// afterwards.
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	getBytesBufferPool().Put(buf)
+}
@@ -0,0 +1,624 @@
+// Package config builds log handler trees from a declarative
+// description, read from a Go value or a JSON or YAML file, and reloads
+// them while the program runs.
+//
+// A YAML config looks like this:
+//
+//     level: info
+//     rules: "db/*=trace"
+//     loggers:
+//       http: warn
+//     handlers:
+//       - type: rotate
+//         path: /var/log/app.log
+//         format: json
+//         rotate: {max_size: 100, max_age: 7, max_backups: 10, compress: true}
+//         async: {queue_size: 4096, overflow: drop_below_level}
+//       - type: stderr
+//         level: error
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/wuzuoliang/log"
+	"github.com/wuzuoliang/log/lumberjack.v2"
+	"gopkg.in/yaml.v2"
+)
+
+// Config describes the root logger: its level, level rules, the levels
+// of named loggers and its handler tree.
+type Config struct {
+	// Level is the root logger's level, trace if empty.
+	Level string `json:"level" yaml:"level"`
+	// Rules is a log.SetLevelRules spec.
+	Rules string `json:"rules" yaml:"rules"`
+	// Loggers maps logger names, as given to log.Named, to levels.
+	Loggers map[string]string `json:"loggers" yaml:"loggers"`
+	// Keys sets the root logger's key names, see log.SetKeyNames.
+	Keys *Keys `json:"keys" yaml:"keys"`
+	// Handlers are the outputs records are written to. More than one
+	// output makes a MultiHandler.
+	Handlers []Output `json:"handlers" yaml:"handlers"`
+}
+
+// Output describes one handler of the tree.
+type Output struct {
+	// Type is one of stdout, stderr, file, rotate, net, syslog, discard,
+	// multi and failover.
+	Type string `json:"type" yaml:"type"`
+	// Format is terminal, logfmt, json or json_pretty. It defaults to
+	// logfmt, or for stdout and stderr to terminal on a terminal.
+	Format string `json:"format" yaml:"format"`
+	// Level only lets records this severe or more through.
+	Level string `json:"level" yaml:"level"`
+	// Caller adds the call site to each record: file, func or stack.
+	Caller string `json:"caller" yaml:"caller"`
+	// Keys overrides the key names for this output's format, see
+	// log.KeyNamesFormat.
+	Keys *Keys `json:"keys" yaml:"keys"`
+	// TimeFormat is a time.Format layout, or epoch, epoch_ms or epoch_ns;
+	// see log.SetTimeFormat. TimeZone is a time zone name such as UTC
+	// or Asia/Shanghai which times are converted to.
+	TimeFormat string `json:"time_format" yaml:"time_format"`
+	TimeZone   string `json:"time_zone" yaml:"time_zone"`
+
+	// Path is the file written by file and rotate outputs.
+	Path string `json:"path" yaml:"path"`
+	// Network and Addr are the destination of net and syslog outputs.
+	// A syslog output without Addr writes to the local daemon.
+	Network string `json:"network" yaml:"network"`
+	Addr    string `json:"addr" yaml:"addr"`
+	// Tag and Facility configure syslog outputs.
+	Tag      string `json:"tag" yaml:"tag"`
+	Facility string `json:"facility" yaml:"facility"`
+
+	Rotate *Rotate `json:"rotate" yaml:"rotate"`
+	Async  *Async  `json:"async" yaml:"async"`
+
+	// Handlers are the children of multi and failover outputs.
+	Handlers []Output `json:"handlers" yaml:"handlers"`
+}
+
+// Rotate holds the rotation settings of a rotate output; see
+// log.RotateOptions.
+type Rotate struct {
+	MaxSize    *int  `json:"max_size" yaml:"max_size"`       // megabytes
+	MaxAge     *int  `json:"max_age" yaml:"max_age"`         // days
+	MaxBackups *int  `json:"max_backups" yaml:"max_backups"` // files
+	Compress   *bool `json:"compress" yaml:"compress"`
+	DayRotate  *bool `json:"day_rotate" yaml:"day_rotate"`
+	// Interval is minute, hour, day, week or a cron expression; see
+	// lumberjack.ParseSchedule.
+	Interval string `json:"interval" yaml:"interval"`
+	// TimeZone is the IANA time zone periods start in.
+	TimeZone string `json:"time_zone" yaml:"time_zone"`
+	// BackupNaming is time or number; see lumberjack.RotateOption.
+	BackupNaming string `json:"backup_naming" yaml:"backup_naming"`
+	// BackupLayout is the time layout of the names of backups.
+	BackupLayout string `json:"backup_layout" yaml:"backup_layout"`
+	// BackupDir is the directory backups are moved to.
+	BackupDir string `json:"backup_dir" yaml:"backup_dir"`
+	// BackupDateDir is the time layout of date-partitioned directories
+	// under BackupDir, such as 2006/01/02.
+	BackupDateDir string `json:"backup_date_dir" yaml:"backup_date_dir"`
+	// Codec is gzip, zstd or none, compressing at Level, the codec's
+	// default if 0, with Concurrency goroutines per backup.
+	Codec       string `json:"codec" yaml:"codec"`
+	Level       int    `json:"level" yaml:"level"`
+	Concurrency int    `json:"concurrency" yaml:"concurrency"`
+	// MaxTotalSize caps the file and its backups in megabytes, and
+	// MinFreeSpace is the megabytes to leave free on the disk.
+	MaxTotalSize int `json:"max_total_size" yaml:"max_total_size"`
+	MinFreeSpace int `json:"min_free_space" yaml:"min_free_space"`
+	// DiskFull is write, stop or sample, what is done with writes while
+	// less than MinFreeSpace is free; sample writes one in DiskFullRate.
+	DiskFull     string `json:"disk_full" yaml:"disk_full"`
+	DiskFullRate int    `json:"disk_full_rate" yaml:"disk_full_rate"`
+}
+
+// Keys renames the built-in fields of records and picks which of them
+// are written; see log.RecordKeyNames. Empty names keep their defaults.
+type Keys struct {
+	Time      string `json:"time" yaml:"time"`
+	Level     string `json:"level" yaml:"level"`
+	Msg       string `json:"msg" yaml:"msg"`
+	Call      string `json:"call" yaml:"call"`
+	RequestID string `json:"request_id" yaml:"request_id"`
+	SpanID    string `json:"span_id" yaml:"span_id"`
+	ParentID  string `json:"parent_id" yaml:"parent_id"`
+	// Order lists the built-in fields to write, by their default names:
+	// time, level, msg, call, request_id, span_id and parent_id.
+	Order []string `json:"order" yaml:"order"`
+}
+
+// Async makes an output asynchronous; see log.AsyncHandler.
+type Async struct {
+	QueueSize int `json:"queue_size" yaml:"queue_size"`
+	// Overflow is block, drop_newest, drop_oldest or drop_below_level.
+	Overflow string `json:"overflow" yaml:"overflow"`
+	// DropLevel is the level kept by drop_below_level.
+	DropLevel string `json:"drop_level" yaml:"drop_level"`
+}
+
+var overflows = map[string]log.OverflowPolicy{
+	"":                 log.OverflowBlock,
+	"block":            log.OverflowBlock,
+	"drop_newest":      log.OverflowDropNewest,
+	"drop_oldest":      log.OverflowDropOldest,
+	"drop_below_level": log.OverflowDropBelowLevel,
+}
+
+var facilities = map[string]log.Facility{
+	"kern": log.FacilityKern, "user": log.FacilityUser, "mail": log.FacilityMail,
+	"daemon": log.FacilityDaemon, "auth": log.FacilityAuth, "syslog": log.FacilitySyslog,
+	"lpr": log.FacilityLPR, "news": log.FacilityNews, "uucp": log.FacilityUUCP,
+	"cron": log.FacilityCron, "authpriv": log.FacilityAuthPriv, "ftp": log.FacilityFTP,
+	"local0": log.FacilityLocal0, "local1": log.FacilityLocal1,
+	"local2": log.FacilityLocal2, "local3": log.FacilityLocal3,
+	"local4": log.FacilityLocal4, "local5": log.FacilityLocal5,
+	"local6": log.FacilityLocal6, "local7": log.FacilityLocal7,
+}
+
+// Load reads a config file. Files ending in .json are JSON, anything
+// else is YAML, which JSON is also valid as.
+func Load(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c *Config
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		c, err = ParseJSON(data)
+	} else {
+		c, err = ParseYAML(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return c, nil
+}
+
+// ParseJSON decodes a JSON config. Unknown keys are an error.
+func ParseJSON(data []byte) (*Config, error) {
+	c := new(Config)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ParseYAML decodes a YAML config. Unknown keys are an error.
+func ParseYAML(data []byte) (*Config, error) {
+	c := new(Config)
+	if err := yaml.UnmarshalStrict(data, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Errors lists every problem Validate found.
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validate checks c without opening any files or connections. Each
+// problem is reported with the position of the offending value, such
+// as `handlers[1].handlers[0].level: unknown level "verbose"`; the
+// returned error is an Errors holding all of them.
+func (c *Config) Validate() error {
+	var errs Errors
+	add := func(where string, err error) {
+		errs = append(errs, fmt.Errorf("%s: %v", where, err))
+	}
+
+	if c.Level != "" {
+		if _, err := log.ParseLevel(c.Level); err != nil {
+			add("level", err)
+		}
+	}
+	if err := log.CheckLevelRules(c.Rules); err != nil {
+		add("rules", err)
+	}
+	for name, level := range c.Loggers {
+		if _, err := log.ParseLevel(level); err != nil {
+			add(fmt.Sprintf("loggers.%s", name), err)
+		}
+	}
+	c.Keys.validate("keys", add)
+	if len(c.Handlers) == 0 {
+		add("handlers", fmt.Errorf("no handlers"))
+	}
+	for i := range c.Handlers {
+		c.Handlers[i].validate(fmt.Sprintf("handlers[%d]", i), add)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (o *Output) validate(where string, add func(string, error)) {
+	if _, err := format(o.Format, nil); err != nil {
+		add(where+".format", err)
+	}
+	if o.TimeZone != "" {
+		if _, err := time.LoadLocation(o.TimeZone); err != nil {
+			add(where+".time_zone", err)
+		}
+	}
+	if o.Level != "" {
+		if _, err := log.ParseLevel(o.Level); err != nil {
+			add(where+".level", err)
+		}
+	}
+	o.Keys.validate(where+".keys", add)
+	switch o.Caller {
+	case "", "file", "func", "stack":
+	default:
+		add(where+".caller", fmt.Errorf("unknown caller %q, want file, func or stack", o.Caller))
+	}
+	if o.Async != nil {
+		if _, ok := overflows[o.Async.Overflow]; !ok {
+			add(where+".async.overflow", fmt.Errorf("unknown overflow policy %q", o.Async.Overflow))
+		}
+		if o.Async.DropLevel != "" {
+			if _, err := log.ParseLevel(o.Async.DropLevel); err != nil {
+				add(where+".async.drop_level", err)
+			}
+		}
+	}
+
+	need := func(field, value string) {
+		if value == "" {
+			add(where+"."+field, fmt.Errorf("required for %s outputs", o.Type))
+		}
+	}
+	parent := false
+	switch o.Type {
+	case "stdout", "stderr", "discard":
+	case "file", "rotate":
+		need("path", o.Path)
+	case "net":
+		need("network", o.Network)
+		need("addr", o.Addr)
+	case "syslog":
+		if o.Facility != "" {
+			if _, ok := facilities[o.Facility]; !ok {
+				add(where+".facility", fmt.Errorf("unknown facility %q", o.Facility))
+			}
+		}
+	case "multi", "failover":
+		parent = true
+		if len(o.Handlers) == 0 {
+			add(where+".handlers", fmt.Errorf("required for %s outputs", o.Type))
+		}
+	case "":
+		add(where+".type", fmt.Errorf("missing"))
+	default:
+		add(where+".type", fmt.Errorf("unknown type %q", o.Type))
+	}
+	// settings of the format need an output which has one
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{
+		{"keys", o.Keys != nil},
+		{"time_format", o.TimeFormat != ""},
+		{"time_zone", o.TimeZone != ""},
+	} {
+		switch {
+		case !f.set:
+		case o.Type == "syslog" || o.Type == "discard" || parent:
+			add(where+"."+f.name, fmt.Errorf("not valid for %s outputs", o.Type))
+		case o.Format == "" && (o.Type == "stdout" || o.Type == "stderr"):
+			add(where+"."+f.name, fmt.Errorf("requires a format"))
+		}
+	}
+	if o.Rotate != nil && o.Type != "rotate" {
+		add(where+".rotate", fmt.Errorf("only valid for rotate outputs"))
+	}
+	if r := o.Rotate; r != nil {
+		if r.Interval != "" {
+			if _, err := lumberjack.ParseSchedule(r.Interval); err != nil {
+				add(where+".rotate.interval", err)
+			}
+		}
+		if r.TimeZone != "" {
+			if _, err := time.LoadLocation(r.TimeZone); err != nil {
+				add(where+".rotate.time_zone", err)
+			}
+		}
+		switch r.BackupNaming {
+		case "", lumberjack.NamingTime, lumberjack.NamingNumber:
+		default:
+			add(where+".rotate.backup_naming", fmt.Errorf("unknown naming %q", r.BackupNaming))
+		}
+		if _, err := lumberjack.NewCodec(r.Codec, r.Level, r.Concurrency); err != nil {
+			add(where+".rotate.codec", err)
+		}
+		switch r.DiskFull {
+		case "", lumberjack.DiskFullWrite, lumberjack.DiskFullStop, lumberjack.DiskFullSample:
+		default:
+			add(where+".rotate.disk_full", fmt.Errorf("unknown policy %q", r.DiskFull))
+		}
+	}
+	if !parent && len(o.Handlers) > 0 {
+		add(where+".handlers", fmt.Errorf("only valid for multi and failover outputs"))
+	}
+	for i := range o.Handlers {
+		o.Handlers[i].validate(fmt.Sprintf("%s.handlers[%d]", where, i), add)
+	}
+}
+
+// Build validates c and builds its handler tree. If opening an output
+// fails, the outputs already opened are closed again.
+func (c *Config) Build() (log.Handler, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	if len(c.Handlers) == 1 {
+		return c.Handlers[0].build("handlers[0]")
+	}
+	return buildAll("handlers", c.Handlers, log.MultiHandler)
+}
+
+// Apply builds c's handler tree and installs it, with c's levels, key
+// names and rules, on the root logger. The handler tree it replaces is
+// flushed and closed, so Apply takes ownership of whatever handler the
+// root logger had. Nothing changes if c is invalid or an output can't
+// be opened.
+//
+// Named loggers which c no longer mentions keep their current level.
+func (c *Config) Apply() error {
+	h, err := c.Build()
+	if err != nil {
+		return err
+	}
+
+	root := log.Root()
+	old := root.GetHandler()
+	root.SetHandler(h)
+
+	level := log.Level(log.LvlTrace)
+	if c.Level != "" {
+		level, _ = log.ParseLevel(c.Level)
+	}
+	root.SetOutLevel(level)
+	root.SetKeyNames(c.Keys.names())
+	log.SetLevelRules(c.Rules)
+	for name, s := range c.Loggers {
+		lvl, _ := log.ParseLevel(s)
+		log.Named(name).SetOutLevel(lvl)
+	}
+
+	err = log.FlushHandler(old)
+	if errClose := log.CloseHandler(old); err == nil && errClose != nil {
+		err = errClose
+	}
+	return err
+}
+
+func (o *Output) build(where string) (h log.Handler, err error) {
+	fmtr, _ := format(o.Format, o.formatOptions())
+	if o.Keys != nil {
+		fmtr = log.KeyNamesFormat(o.Keys.names(), fmtr)
+	}
+	switch o.Type {
+	case "stdout":
+		h = log.StdoutHandler
+		if o.Format != "" {
+			h = log.StreamHandler(os.Stdout, fmtr)
+		}
+	case "stderr":
+		h = log.StderrHandler
+		if o.Format != "" {
+			h = log.StreamHandler(os.Stderr, fmtr)
+		}
+	case "discard":
+		h = log.DiscardHandler()
+	case "file":
+		h, err = log.FileHandler(o.Path, fmtr)
+	case "rotate":
+		h, err = log.FileHandlerRotate(o.Path, fmtr, o.Rotate.options())
+	case "net":
+		h = log.NetHandlerReconnect(o.Network, o.Addr, fmtr)
+	case "syslog":
+		opts := []log.SyslogOptions{}
+		if o.Tag != "" {
+			opts = append(opts, log.SetSyslogTag(o.Tag))
+		}
+		if o.Facility != "" {
+			opts = append(opts, log.SetSyslogFacility(facilities[o.Facility]))
+		}
+		if o.Addr == "" {
+			h, err = log.SyslogHandler(opts...)
+		} else {
+			network := o.Network
+			if network == "" {
+				network = "udp"
+			}
+			h = log.SyslogNetHandler(network, o.Addr, opts...)
+		}
+	case "multi":
+		h, err = buildAll(where+".handlers", o.Handlers, log.MultiHandler)
+	case "failover":
+		h, err = buildAll(where+".handlers", o.Handlers, log.FailoverHandler)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", where, err)
+	}
+
+	switch o.Caller {
+	case "file":
+		h = log.CallerFileHandler(h)
+	case "func":
+		h = log.CallerFuncHandler(h)
+	case "stack":
+		h = log.CallerStackHandler("%+v", h)
+	}
+	if a := o.Async; a != nil {
+		opts := []log.AsyncOptions{log.SetAsyncOverflow(overflows[a.Overflow])}
+		if a.QueueSize > 0 {
+			opts = append(opts, log.SetAsyncQueueSize(a.QueueSize))
+		}
+		if a.DropLevel != "" {
+			lvl, _ := log.ParseLevel(a.DropLevel)
+			opts = append(opts, log.SetAsyncDropLevel(lvl))
+		}
+		h = log.AsyncHandler(h, opts...)
+	}
+	if o.Level != "" {
+		lvl, _ := log.ParseLevel(o.Level)
+		h = log.LvlFilterHandler(lvl, h)
+	}
+	return h, nil
+}
+
+func buildAll(where string, outputs []Output, join func(...log.Handler) log.Handler) (log.Handler, error) {
+	hs := make([]log.Handler, 0, len(outputs))
+	for i := range outputs {
+		h, err := outputs[i].build(fmt.Sprintf("%s[%d]", where, i))
+		if err != nil {
+			for _, h := range hs {
+				log.CloseHandler(h)
+			}
+			return nil, err
+		}
+		hs = append(hs, h)
+	}
+	return join(hs...), nil
+}
+
+func (k *Keys) validate(where string, add func(string, error)) {
+	if k == nil {
+		return
+	}
+	for i, s := range k.Order {
+		var key log.RecordKey
+		if err := key.UnmarshalText([]byte(s)); err != nil {
+			add(fmt.Sprintf("%s.order[%d]", where, i), err)
+		}
+	}
+}
+
+// names returns the key names k describes, the defaults if k is nil.
+func (k *Keys) names() log.RecordKeyNames {
+	if k == nil {
+		return log.RecordKeyNames{}
+	}
+	names := log.RecordKeyNames{
+		Time:      k.Time,
+		Level:     k.Level,
+		Msg:       k.Msg,
+		Call:      k.Call,
+		RequestID: k.RequestID,
+		SpanID:    k.SpanID,
+		ParentID:  k.ParentID,
+	}
+	if k.Order != nil {
+		names.Order = make([]log.RecordKey, len(k.Order))
+		for i, s := range k.Order {
+			names.Order[i].UnmarshalText([]byte(s))
+		}
+	}
+	return names
+}
+
+func (r *Rotate) options() []log.RotateOptions {
+	if r == nil {
+		return nil
+	}
+	var opts []log.RotateOptions
+	if r.MaxSize != nil {
+		opts = append(opts, log.SetMaxSize(*r.MaxSize))
+	}
+	if r.MaxAge != nil {
+		opts = append(opts, log.SetMaxSaveDay(*r.MaxAge))
+	}
+	if r.MaxBackups != nil {
+		opts = append(opts, log.SetMaxBackup(*r.MaxBackups))
+	}
+	if r.Compress != nil {
+		opts = append(opts, log.SetCompress(*r.Compress))
+	}
+	if r.DayRotate != nil {
+		opts = append(opts, log.SetDayRotate(*r.DayRotate))
+	}
+	if r.Interval != "" {
+		opts = append(opts, log.SetRotateInterval(r.Interval))
+	}
+	if r.TimeZone != "" {
+		opts = append(opts, log.SetRotateTimeZone(r.TimeZone))
+	}
+	if r.BackupNaming != "" {
+		opts = append(opts, log.SetBackupNaming(r.BackupNaming))
+	}
+	if r.BackupLayout != "" {
+		opts = append(opts, log.SetBackupLayout(r.BackupLayout))
+	}
+	if r.BackupDir != "" {
+		opts = append(opts, log.SetBackupDir(r.BackupDir))
+	}
+	if r.BackupDateDir != "" {
+		opts = append(opts, log.SetBackupDateDir(r.BackupDateDir))
+	}
+	if r.Codec != "" || r.Level != 0 {
+		opts = append(opts, log.SetCompressCodec(r.Codec, r.Level))
+	}
+	if r.Concurrency != 0 {
+		opts = append(opts, log.SetCompressConcurrency(r.Concurrency))
+	}
+	if r.MaxTotalSize != 0 {
+		opts = append(opts, log.SetMaxTotalSize(r.MaxTotalSize))
+	}
+	if r.MinFreeSpace != 0 {
+		opts = append(opts, log.SetMinFreeSpace(r.MinFreeSpace))
+	}
+	if r.DiskFull != "" || r.DiskFullRate != 0 {
+		opts = append(opts, log.SetDiskFullPolicy(r.DiskFull, r.DiskFullRate))
+	}
+	return opts
+}
+
+func (o *Output) formatOptions() []log.FormatOptions {
+	var opts []log.FormatOptions
+	if o.TimeFormat != "" {
+		opts = append(opts, log.SetTimeFormat(o.TimeFormat))
+	}
+	if o.TimeZone != "" {
+		loc, _ := time.LoadLocation(o.TimeZone)
+		opts = append(opts, log.SetTimeLocation(loc))
+	}
+	return opts
+}
+
+func format(name string, opts []log.FormatOptions) (log.Format, error) {
+	switch name {
+	case "", "logfmt":
+		return log.LogfmtFormat(opts...), nil
+	case "terminal":
+		return log.TerminalFormat(opts...), nil
+	case "json":
+		return log.JsonFormat(opts...), nil
+	case "json_pretty":
+		return log.JsonFormatEx(true, true, opts...), nil
+	}
+	return nil, fmt.Errorf("unknown format %q, want terminal, logfmt, json or json_pretty", name)
+}
@@ -0,0 +1,289 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/wuzuoliang/log"
+)
+
+const testYAML = `
+level: info
+rules: "config.sub=error"
+loggers:
+  config: debug
+keys: {level: severity}
+handlers:
+  - type: rotate
+    path: %s
+    format: json
+    keys: {msg: message, order: [time, level, msg]}
+    time_format: epoch_ms
+    rotate: {max_size: 1, max_backups: 2}
+  - type: failover
+    level: error
+    handlers:
+      - type: discard
+`
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "log-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// withRoot runs fn with the root logger writing to a discard handler, so
+// that Apply doesn't close the test binary's real handler.
+func withRoot(fn func()) {
+	old := log.Root().GetHandler()
+	log.Root().SetHandler(log.DiscardHandler())
+	defer func() {
+		log.CloseHandler(log.Root().GetHandler())
+		log.Root().SetHandler(old)
+		log.SetOutLevel(log.LvlTrace)
+		log.SetLevelRules("")
+		log.SetKeyNames(log.RecordKeyNames{})
+	}()
+	fn()
+}
+
+func TestParse(t *testing.T) {
+	y, err := ParseYAML([]byte(strings.Replace(testYAML, "%s", "app.log", 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := ParseJSON([]byte(`{"level": "info", "rules": "config.sub=error", "loggers": {"config": "debug"},
+		"keys": {"level": "severity"},
+		"handlers": [{"type": "rotate", "path": "app.log", "format": "json", "keys": {"msg": "message", "order": ["time", "level", "msg"]}, "time_format": "epoch_ms", "rotate": {"max_size": 1, "max_backups": 2}},
+			{"type": "failover", "level": "error", "handlers": [{"type": "discard"}]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []*Config{y, j} {
+		if err := c.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		if c.Level != "info" || c.Loggers["config"] != "debug" || len(c.Handlers) != 2 ||
+			*c.Handlers[0].Rotate.MaxSize != 1 || c.Handlers[0].Rotate.MaxAge != nil ||
+			c.Keys.Level != "severity" || len(c.Handlers[0].Keys.Order) != 3 || c.Handlers[0].TimeFormat != "epoch_ms" ||
+			c.Handlers[1].Handlers[0].Type != "discard" {
+			t.Fatalf("unexpected config %+v", c)
+		}
+	}
+
+	if _, err := ParseYAML([]byte("handler: []")); err == nil {
+		t.Fatal("unknown YAML key accepted")
+	}
+	if _, err := ParseJSON([]byte(`{"handler": []}`)); err == nil {
+		t.Fatal("unknown JSON key accepted")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	c := &Config{
+		Level: "loud",
+		Rules: "db",
+		Handlers: []Output{
+			{Type: "file"},
+			{Type: "multi", Handlers: []Output{
+				{Type: "fiel"},
+				{Type: "stdout", Format: "xml", Rotate: &Rotate{}},
+			}},
+			{Type: "stderr", Async: &Async{Overflow: "spill"}},
+			{Type: "stderr", Keys: &Keys{Order: []string{"ts"}}},
+			{Type: "file", Path: "x.log", TimeZone: "Mars/Olympus"},
+			{Type: "rotate", Path: "x.log", Rotate: &Rotate{Interval: "fortnight", TimeZone: "Mars/Olympus", BackupNaming: "random", Codec: "lz4", DiskFull: "panic"}},
+		},
+	}
+	err := c.Validate()
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatalf("expected Errors, got %v", err)
+	}
+	want := []string{
+		`level: log: unknown level "loud"`,
+		`rules: log: bad level rule "db"`,
+		`handlers[0].path: required for file outputs`,
+		`handlers[1].handlers[0].type: unknown type "fiel"`,
+		`handlers[1].handlers[1].format: unknown format "xml"`,
+		`handlers[1].handlers[1].rotate: only valid for rotate outputs`,
+		`handlers[2].async.overflow: unknown overflow policy "spill"`,
+		`handlers[3].keys.order[0]: log: unknown record key "ts"`,
+		`handlers[3].keys: requires a format`,
+		`handlers[4].time_zone: unknown time zone Mars/Olympus`,
+		`handlers[5].rotate.interval: bad interval "fortnight"`,
+		`handlers[5].rotate.time_zone: unknown time zone Mars/Olympus`,
+		`handlers[5].rotate.backup_naming: unknown naming "random"`,
+		`handlers[5].rotate.codec: bad compress codec "lz4"`,
+		`handlers[5].rotate.disk_full: unknown policy "panic"`,
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %d: %v", len(want), len(errs), err)
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(errs[i].Error(), w) {
+			t.Errorf("error %d: expected %q, got %q", i, w, errs[i])
+		}
+	}
+
+	if err := (&Config{}).Validate(); err == nil || err.Error() != "handlers: no handlers" {
+		t.Fatalf("empty config: %v", err)
+	}
+}
+
+func TestApply(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "app.log")
+	c, err := ParseYAML([]byte(strings.Replace(testYAML, "%s", out, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withRoot(func() {
+		if err := c.Apply(); err != nil {
+			t.Fatal(err)
+		}
+		log.SetClock(func() time.Time { return time.Unix(1, 0) })
+		defer log.SetClock(nil)
+		log.Debug("dropped")
+		log.Info("kept")
+		log.Named("config").Debug("named")
+		log.Named("config.sub").Warn("ruled out")
+
+		if got := log.Named("config.child").GetOutLevel(); got != log.LvlDebug {
+			t.Errorf("config.child: expected debug, got %v", got)
+		}
+		if err := (&Config{Handlers: []Output{{Type: "file", Path: filepath.Join(dir, "missing", "x.log")}}}).Apply(); err == nil {
+			t.Error("opening a file in a missing directory succeeded")
+		}
+		log.Info("still")
+	})
+
+	b, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(b)
+	for _, msg := range []string{`{"time":1000,"severity":"info","message":"kept"}`, `"severity":"debug","message":"named"`, `"message":"still"`} {
+		if !strings.Contains(s, msg) {
+			t.Errorf("missing %s in %q", msg, s)
+		}
+	}
+	for _, msg := range []string{"dropped", "ruled out"} {
+		if strings.Contains(s, msg) {
+			t.Errorf("unexpected %s in %q", msg, s)
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	cfg := filepath.Join(dir, "log.yaml")
+	first, second := filepath.Join(dir, "first.log"), filepath.Join(dir, "second.log")
+	write := func(level, out string) {
+		data := "level: " + level + "\nhandlers:\n  - type: file\n    path: " + out + "\n"
+		if err := ioutil.WriteFile(cfg, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("info", first)
+
+	withRoot(func() {
+		errs := make(chan error, 10)
+		w, err := Watch(cfg, SetWatchInterval(10*time.Millisecond), SetWatchSignals(), nil,
+			SetWatchErrorHook(func(err error) { errs <- err }))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer w.Close()
+		log.Info("one")
+
+		// a broken file keeps the previous config
+		if err := ioutil.WriteFile(cfg, []byte("level: [\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-errs:
+		case <-time.After(5 * time.Second):
+			t.Fatal("broken config not reported")
+		}
+		log.Info("two")
+
+		write("warn", second)
+		deadline := time.Now().Add(5 * time.Second)
+		for log.GetLogLevel() != log.LvlWarn {
+			if time.Now().After(deadline) {
+				t.Fatal("config change not picked up")
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		log.Info("dropped")
+		log.Warn("three")
+
+		write("debug", second)
+		if err := w.Reload(); err != nil || log.GetLogLevel() != log.LvlDebug {
+			t.Fatalf("Reload: %v, level %v", err, log.GetLogLevel())
+		}
+	})
+
+	for out, msgs := range map[string][]string{first: {"one", "two"}, second: {"three"}} {
+		b, _ := ioutil.ReadFile(out)
+		for _, msg := range msgs {
+			if !strings.Contains(string(b), "msg="+msg) {
+				t.Errorf("missing %s in %s: %q", msg, filepath.Base(out), b)
+			}
+		}
+		if strings.Contains(string(b), "dropped") {
+			t.Errorf("unexpected record in %s: %q", filepath.Base(out), b)
+		}
+	}
+}
+
+func TestTwoRotateOutputs(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	a, b := filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log")
+	c := &Config{Handlers: []Output{
+		{Type: "rotate", Path: a, Format: "logfmt", Rotate: &Rotate{BackupDir: "archive"}},
+		{Type: "rotate", Path: b, Format: "logfmt"},
+	}}
+
+	withRoot(func() {
+		if err := c.Apply(); err != nil {
+			t.Fatal(err)
+		}
+		log.Info("rotated")
+
+		var rotate func(h log.Handler)
+		rotate = func(h log.Handler) {
+			if r, ok := h.(interface{ Rotate() error }); ok {
+				if err := r.Rotate(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, child := range log.UnwrapHandler(h) {
+				rotate(child)
+			}
+		}
+		rotate(log.Root().GetHandler())
+	})
+
+	// the second output keeps its backups next to it, not in the first
+	// one's backup directory
+	archived, _ := filepath.Glob(filepath.Join(dir, "archive", "a-*.log*"))
+	if len(archived) != 1 {
+		t.Errorf("expected a backup of a.log in archive, got %v", archived)
+	}
+	backups, _ := filepath.Glob(filepath.Join(dir, "b-*.log*"))
+	if len(backups) != 1 {
+		t.Errorf("expected a backup of b.log next to it, got %v", backups)
+	}
+}
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/wuzuoliang/log"
+)
+
+// watchOptions storage config watcher parameters
+type watchOptions struct {
+	interval time.Duration // 检查文件变更的间隔, 0 表示不检查
+	signals  []os.Signal   // 触发重新加载的信号
+	onError  func(error)   // 重新加载失败时调用
+}
+
+var _defaultWatchOptions = watchOptions{
+	interval: 2 * time.Second,
+	signals:  []os.Signal{syscall.SIGHUP},
+	onError: func(err error) {
+		log.Error("log config reload failed, keeping the previous config", "err", err)
+	},
+}
+
+// WatchOptions configures Watch.
+type WatchOptions func(*watchOptions)
+
+// SetWatchInterval sets how often the file is checked for changes.
+// Zero turns checking off, leaving only signals and Reload.
+func SetWatchInterval(d time.Duration) WatchOptions {
+	return func(o *watchOptions) {
+		o.interval = d
+	}
+}
+
+// SetWatchSignals sets the signals which reload the file, SIGHUP by
+// default. No signals turns signal handling off.
+func SetWatchSignals(sigs ...os.Signal) WatchOptions {
+	return func(o *watchOptions) {
+		o.signals = sigs
+	}
+}
+
+// SetWatchErrorHook sets the function told about failed reloads. By
+// default they are logged at error level through the root logger.
+func SetWatchErrorHook(fn func(error)) WatchOptions {
+	return func(o *watchOptions) {
+		o.onError = fn
+	}
+}
+
+// Watcher reloads a config file when it changes.
+type Watcher struct {
+	path string
+	opts watchOptions
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+
+	sigs chan os.Signal
+	quit chan struct{}
+	done chan struct{}
+}
+
+// Watch loads and applies the config file at path, then reloads it
+// whenever it changes or the process receives SIGHUP. A reload which
+// fails, because the file is invalid or an output can't be opened,
+// leaves the previous config in place.
+//
+// Records logged while a reload runs go to either the old or the new
+// handler tree. The root logger's handler is swapped before the old tree
+// is flushed and closed, but a record in flight during the swap may
+// still reach the old tree as it closes, and be dropped.
+func Watch(path string, opts ...WatchOptions) (*Watcher, error) {
+	w := &Watcher{
+		path: path,
+		opts: _defaultWatchOptions,
+		quit: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(&w.opts)
+	}
+	if err := w.Reload(); err != nil {
+		return nil, err
+	}
+
+	if len(w.opts.signals) > 0 {
+		w.sigs = make(chan os.Signal, 1)
+		signal.Notify(w.sigs, w.opts.signals...)
+	}
+	go w.loop()
+	return w, nil
+}
+
+// Reload loads and applies the config file now, whether or not it has
+// changed.
+func (w *Watcher) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	// stat before reading, so a write racing with the load is seen
+	// again by the next check. A file which fails to load is not
+	// retried until it changes again.
+	fi, err := os.Stat(w.path)
+	if err != nil {
+		return err
+	}
+	w.modTime, w.size = fi.ModTime(), fi.Size()
+	c, err := Load(w.path)
+	if err != nil {
+		return err
+	}
+	return c.Apply()
+}
+
+// changed reports whether the file differs from the one last applied.
+func (w *Watcher) changed() bool {
+	fi, err := os.Stat(w.path)
+	if err != nil {
+		// most likely the file is being replaced; try again later
+		return false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return !fi.ModTime().Equal(w.modTime) || fi.Size() != w.size
+}
+
+func (w *Watcher) loop() {
+	defer close(w.done)
+	var tick <-chan time.Time
+	if w.opts.interval > 0 {
+		t := time.NewTicker(w.opts.interval)
+		defer t.Stop()
+		tick = t.C
+	}
+	for {
+		select {
+		case <-w.quit:
+			return
+		case <-w.sigs:
+		case <-tick:
+			if !w.changed() {
+				continue
+			}
+		}
+		if err := w.Reload(); err != nil && w.opts.onError != nil {
+			w.opts.onError(err)
+		}
+	}
+}
+
+// Close stops watching. The config last applied stays in place.
+func (w *Watcher) Close() error {
+	if w.sigs != nil {
+		signal.Stop(w.sigs)
+	}
+	close(w.quit)
+	<-w.done
+	return nil
+}
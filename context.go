@@ -0,0 +1,84 @@
+package log
+
+import "context"
+
+// ctxKey is the type of the keys this package stores in contexts.
+type ctxKey int
+
+const (
+	loggerCtxKey ctxKey = iota
+	fieldsCtxKey
+)
+
+// ctxFields are the key/value pairs and Fields attached to a context
+// with ContextWith, including those of the contexts it derives from.
+type ctxFields struct {
+	keyValues []interface{}
+	fields    []Field
+}
+
+// WithContext returns a copy of ctx carrying l, which FromContext
+// returns. Store a logger, with the key/value pairs of the request it
+// logs for, once when a request comes in:
+//
+//     ctx = log.WithContext(ctx, log.New("method", r.Method, "path", r.URL.Path))
+//
+// and get it back wherever the request's context is passed:
+//
+//     log.FromContext(ctx).InfoContext(ctx, "user loaded", "id", id)
+//
+func WithContext(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, loggerCtxKey, l)
+}
+
+// FromContext returns the logger stored in ctx by WithContext, or the
+// root logger if there is none.
+func FromContext(ctx context.Context) Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(loggerCtxKey).(Logger); ok {
+			return l
+		}
+	}
+	return root
+}
+
+// ContextWith returns a copy of ctx carrying keyValues, which may hold
+// Fields, in addition to those attached to ctx already. The *Context
+// methods and LogFields of every logger write them into their records,
+// after the logger's own pairs and before those of the log call, so
+//
+//     ctx = log.ContextWith(ctx, "tenant", tenant)
+//     log.InfoContext(ctx, "quota checked")
+//
+// writes tenant=... without the logger having to know about it.
+func ContextWith(ctx context.Context, keyValues ...interface{}) context.Context {
+	kvs, fs := splitFields(keyValues)
+	var parent ctxFields
+	if cf := contextFields(ctx); cf != nil {
+		parent = *cf
+	}
+	return context.WithValue(ctx, fieldsCtxKey, &ctxFields{
+		keyValues: newKeyValues(parent.keyValues, kvs),
+		fields:    newFields(parent.fields, fs),
+	})
+}
+
+// contextFields returns the pairs attached to ctx with ContextWith, or
+// nil if there are none.
+func contextFields(ctx context.Context) *ctxFields {
+	if ctx == nil {
+		return nil
+	}
+	cf, _ := ctx.Value(fieldsCtxKey).(*ctxFields)
+	return cf
+}
+
+// contextPrefix returns l's key/value pairs and Fields followed by those
+// attached to ctx.
+func (l *logger) contextPrefix(ctx context.Context) ([]interface{}, []Field) {
+	cf := contextFields(ctx)
+	if cf == nil {
+		return l.KeyValues, l.fields
+	}
+	return newKeyValues(l.KeyValues, cf.keyValues), newFields(l.fields, cf.fields)
+}
@@ -0,0 +1,53 @@
+package log
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestFromContext(t *testing.T) {
+	t.Parallel()
+
+	if FromContext(context.Background()) != Root() || FromContext(nil) != Root() {
+		t.Fatal("expected the root logger without one in the context")
+	}
+	l, _, r := testLogger()
+	l = l.New("req", 7)
+	ctx := WithContext(context.Background(), l)
+	FromContext(ctx).Info("hello", "a", 1)
+	if want := []interface{}{"req", 7, "a", 1}; !reflect.DeepEqual(r.KeyValues, want) {
+		t.Fatalf("got %v, want %v", r.KeyValues, want)
+	}
+}
+
+func TestContextWith(t *testing.T) {
+	t.Parallel()
+
+	l, _, r := testLogger()
+	l = l.New("svc", "api")
+	ctx := ContextWith(context.Background(), "tenant", "acme", Int("shard", 2))
+	ctx = ContextWith(ctx, "user", "bob")
+
+	l.InfoContext(ctx, "hello", "a", 1)
+	if want := []interface{}{"svc", "api", "tenant", "acme", "user", "bob", "a", 1}; !reflect.DeepEqual(r.KeyValues, want) {
+		t.Fatalf("got %v, want %v", r.KeyValues, want)
+	}
+	if len(r.Fields) != 1 || r.Fields[0].Key != "shard" {
+		t.Fatalf("got fields %v", r.Fields)
+	}
+
+	l.LogFields(ctx, LvlInfo, "typed", String("b", "x"))
+	if want := []interface{}{"svc", "api", "tenant", "acme", "user", "bob"}; !reflect.DeepEqual(r.KeyValues, want) {
+		t.Fatalf("got %v, want %v", r.KeyValues, want)
+	}
+	if len(r.Fields) != 2 || r.Fields[0].Key != "shard" || r.Fields[1].Key != "b" {
+		t.Fatalf("got fields %v", r.Fields)
+	}
+
+	// the methods without a context don't see the pairs
+	l.Info("plain")
+	if want := []interface{}{"svc", "api"}; !reflect.DeepEqual(r.KeyValues, want) {
+		t.Fatalf("got %v, want %v", r.KeyValues, want)
+	}
+}
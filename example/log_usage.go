@@ -34,14 +34,9 @@ func main() {
 
 	log.Root().SetHandler(log.StderrHandler)
 	logLevel := "111"
-	switch logLevel {
-	case "debug":
-		log.SetOutLevel(log.LvlDebug)
-	case "info":
-		log.SetOutLevel(log.LvlInfo)
-	case "warn":
-		log.SetOutLevel(log.LvlWarn)
-	default:
+	if lvl, err := log.ParseLevel(logLevel); err == nil {
+		log.SetOutLevel(lvl)
+	} else {
 		log.SetOutLevel(log.LvlTrace)
 	}
 	log.Log("asdasdas")
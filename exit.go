@@ -0,0 +1,65 @@
+package log
+
+import (
+	"os"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+const defaultFatalFlushTimeout = 3 * time.Second
+
+var _exitFuncPtr unsafe.Pointer = unsafe.Pointer(&_defaultExitFunc) // *func(int)
+
+var _defaultExitFunc = os.Exit
+
+// SetExitFunc replaces the function Fatal calls to terminate the program
+// once the handlers have been flushed. It defaults to os.Exit. Tests can
+// install their own function to observe Fatal without the process dying.
+// Passing nil restores os.Exit.
+func SetExitFunc(fn func(code int)) {
+	if fn == nil {
+		fn = _defaultExitFunc
+	}
+	atomic.StorePointer(&_exitFuncPtr, unsafe.Pointer(&fn))
+}
+
+func getExitFunc() func(code int) {
+	return *(*func(int))(atomic.LoadPointer(&_exitFuncPtr))
+}
+
+var _fatalFlushTimeout = int64(defaultFatalFlushTimeout)
+
+// SetFatalFlushTimeout bounds how long Fatal waits for the handlers to
+// flush before exiting. A value <= 0 restores the default of 3 seconds.
+func SetFatalFlushTimeout(d time.Duration) {
+	if d <= 0 {
+		d = defaultFatalFlushTimeout
+	}
+	atomic.StoreInt64(&_fatalFlushTimeout, int64(d))
+}
+
+func getFatalFlushTimeout() time.Duration {
+	return time.Duration(atomic.LoadInt64(&_fatalFlushTimeout))
+}
+
+// FlushAndExit flushes h, waiting at most the fatal flush timeout, and
+// then terminates the program with the given code through the function
+// installed with SetExitFunc. It is what Fatal does after writing its
+// record, exported for handlers such as ext.FatalHandler.
+func FlushAndExit(h Handler, code int) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = FlushHandler(h)
+	}()
+
+	timer := time.NewTimer(getFatalFlushTimeout())
+	select {
+	case <-done:
+	case <-timer.C:
+	}
+	timer.Stop()
+
+	getExitFunc()(code)
+}
@@ -0,0 +1,63 @@
+package log
+
+import (
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// several tests log at LvlFatal; keep them from killing the test binary
+	SetExitFunc(func(int) {})
+	os.Exit(m.Run())
+}
+
+func TestFatalFlushesBeforeExit(t *testing.T) {
+	var exitCode int32 = -1
+	SetExitFunc(func(code int) {
+		atomic.StoreInt32(&exitCode, int32(code))
+	})
+	defer SetExitFunc(func(int) {})
+
+	rec := &closeRecorder{}
+	l := New()
+	l.SetHandler(BufferedHandler(10, rec))
+	l.Fatal("fatal", "x", 1)
+
+	if code := atomic.LoadInt32(&exitCode); code != 1 {
+		t.Fatalf("got exit code %d, expected 1", code)
+	}
+	if rec.logged != 1 || rec.flushed != 1 {
+		t.Fatalf("got logged=%d flushed=%d before exit, expected 1 and 1", rec.logged, rec.flushed)
+	}
+}
+
+type blockingFlusher struct {
+	release chan struct{}
+}
+
+func (h *blockingFlusher) Log(r *Record) error { return nil }
+func (h *blockingFlusher) Flush() error        { <-h.release; return nil }
+
+func TestFatalFlushTimeout(t *testing.T) {
+	var exited int32
+	SetExitFunc(func(code int) {
+		atomic.StoreInt32(&exited, 1)
+	})
+	defer SetExitFunc(func(int) {})
+	SetFatalFlushTimeout(10 * time.Millisecond)
+	defer SetFatalFlushTimeout(0)
+
+	h := &blockingFlusher{make(chan struct{})}
+	defer close(h.release)
+
+	start := time.Now()
+	FlushAndExit(h, 1)
+	if atomic.LoadInt32(&exited) != 1 {
+		t.Fatalf("expected exit func to be called")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("FlushAndExit waited %v for a stuck handler", elapsed)
+	}
+}
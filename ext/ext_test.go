@@ -10,7 +10,7 @@ import (
 func testHandler() (log.Handler, *log.Record) {
 	rec := new(log.Record)
 	return log.FuncHandler(func(r *log.Record) error {
-		*rec = *r
+		*rec = *r.Clone()
 		return nil
 	}), rec
 }
@@ -85,6 +85,48 @@ func TestSpeculativeHandler(t *testing.T) {
 	}
 }
 
+// flushCounter counts the records logged and the flushes.
+type flushCounter struct {
+	logged, flushed int
+}
+
+func (h *flushCounter) Log(r *log.Record) error {
+	h.logged++
+	return nil
+}
+
+func (h *flushCounter) Flush() error {
+	h.flushed++
+	return nil
+}
+
+func TestSpeculativeFlushHandler(t *testing.T) {
+	t.Parallel()
+
+	h := new(flushCounter)
+	spec := SpeculativeHandler(10, h)
+	lg := log.New()
+	lg.SetHandler(spec)
+	lg.Debug("speculative")
+
+	// flushing the handler tree, as Fatal and Shutdown do, dumps the
+	// buffer into the wrapped handler and flushes it
+	if err := log.FlushHandler(spec); err != nil {
+		t.Fatal(err)
+	}
+	if h.logged != 1 || h.flushed != 1 {
+		t.Fatalf("got %d records and %d flushes, want 1 and 1", h.logged, h.flushed)
+	}
+
+	// the buffer is empty after a dump
+	if err := spec.Dump(); err != nil {
+		t.Fatal(err)
+	}
+	if h.logged != 1 || h.flushed != 2 {
+		t.Fatalf("got %d records and %d flushes, want 1 and 2", h.logged, h.flushed)
+	}
+}
+
 func TestErrorHandler(t *testing.T) {
 	t.Parallel()
 
@@ -103,7 +145,36 @@ func TestErrorHandler(t *testing.T) {
 		t.Fatalf("Expected debug level message to be escalated and pass lvlfilter")
 	}
 
-	if r.Lvl != log.LvlError {
+	if r.Level != log.LvlError {
 		t.Fatalf("Expected debug level message to be escalated to LvlError")
 	}
 }
+
+type closeRecorder struct {
+	flushed, closed int
+}
+
+func (h *closeRecorder) Log(r *log.Record) error { return nil }
+func (h *closeRecorder) Flush() error            { h.flushed++; return nil }
+func (h *closeRecorder) Close() error            { h.closed++; return nil }
+
+func TestHotSwapClose(t *testing.T) {
+	t.Parallel()
+
+	old, cur := &closeRecorder{}, &closeRecorder{}
+	h := HotSwapHandler(old)
+	h.Swap(EscalateErrHandler(cur))
+
+	if err := log.FlushHandler(h); err != nil {
+		t.Fatalf("unexpected flush error: %v", err)
+	}
+	if err := log.CloseHandler(h); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+	if old.flushed != 0 || old.closed != 0 {
+		t.Fatalf("swapped-out handler should not be touched")
+	}
+	if cur.flushed != 1 || cur.closed != 1 {
+		t.Fatalf("got flushed=%d closed=%d, expected 1 and 1", cur.flushed, cur.closed)
+	}
+}
@@ -1,7 +1,6 @@
 package ext
 
 import (
-	"os"
 	"sync"
 	"sync/atomic"
 	"unsafe"
@@ -29,14 +28,20 @@ import (
 //     }
 //
 func EscalateErrHandler(h log.Handler) log.Handler {
-	return log.FuncHandler(func(r *log.Record) error {
-		if r.Level > log.LvlError {
+	return chain("ext.EscalateErrHandler", h, func(r *log.Record) error {
+		if r.Level.Compare(log.LvlError) > 0 {
 			for i := 1; i < len(r.KeyValues); i++ {
 				if v, ok := r.KeyValues[i].(error); ok && v != nil {
 					r.Level = log.LvlError
 					break
 				}
 			}
+			for _, f := range r.Fields {
+				if f.Type == log.ErrorType && f.Interface != nil {
+					r.Level = log.LvlError
+					break
+				}
+			}
 		}
 		return h.Log(r)
 	})
@@ -66,13 +71,22 @@ type Speculative struct {
 func (h *Speculative) Log(r *log.Record) error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	h.recs[h.idx] = r
+	h.recs[h.idx] = r.Clone()
 	h.idx = (h.idx + 1) % len(h.recs)
 	h.full = h.full || h.idx == 0
 	return nil
 }
 
-func (h *Speculative) Flush() {
+// Flush dumps the buffered records, see Dump. Because Speculative is a
+// log.Flusher, log.Shutdown and Fatal dump its buffer before the process
+// exits; Close it beforehand to discard the records instead.
+func (h *Speculative) Flush() error {
+	return h.Dump()
+}
+
+// Dump writes every buffered record to the wrapped handler and then
+// flushes it.
+func (h *Speculative) Dump() error {
 	recs := make([]*log.Record, 0)
 	func() {
 		h.mu.Lock()
@@ -91,6 +105,24 @@ func (h *Speculative) Flush() {
 	for _, r := range recs {
 		h.handler.Log(r)
 	}
+	return log.FlushHandler(h.handler)
+}
+
+func (h *Speculative) Unwrap() []log.Handler {
+	return []log.Handler{h.handler}
+}
+
+// Close discards the buffered records and closes the wrapped handler.
+// Call Flush first if the buffered records should be kept.
+func (h *Speculative) Close() error {
+	h.mu.Lock()
+	for i := range h.recs {
+		h.recs[i] = nil
+	}
+	h.full = false
+	h.idx = 0
+	h.mu.Unlock()
+	return log.CloseHandler(h.handler)
 }
 
 // HotSwapHandler wraps another handler that may swapped out
@@ -116,15 +148,63 @@ func (h *HotSwap) Swap(newHandler log.Handler) {
 	atomic.StorePointer(&h.handler, unsafe.Pointer(&newHandler))
 }
 
+// Get returns the handler currently in use.
+func (h *HotSwap) Get() log.Handler {
+	return *(*log.Handler)(atomic.LoadPointer(&h.handler))
+}
+
+// Flush flushes the handler currently in use.
+func (h *HotSwap) Flush() error {
+	return log.FlushHandler(h.Get())
+}
+
+// Close closes the handler currently in use. The handler it replaced
+// on an earlier Swap is the caller's to close.
+func (h *HotSwap) Close() error {
+	return log.CloseHandler(h.Get())
+}
+
+func (h *HotSwap) Unwrap() []log.Handler {
+	return []log.Handler{h.Get()}
+}
+
 // FatalHandler makes critical errors exit the program
-// immediately, much like the log.Fatal* methods from the
-// standard log package
+// once the wrapped handler has been flushed, much like the
+// log.Fatal* methods from the standard log package
 func FatalHandler(h log.Handler) log.Handler {
-	return log.FuncHandler(func(r *log.Record) error {
+	return chain("ext.FatalHandler", h, func(r *log.Record) error {
 		err := h.Log(r)
 		if r.Level == log.LvlFatal {
-			os.Exit(1)
+			log.FlushAndExit(h, 1)
 		}
 		return err
 	})
 }
+
+// chain returns a Handler that logs records with fn and forwards
+// Close and Flush to next.
+func chain(name string, next log.Handler, fn func(r *log.Record) error) log.Handler {
+	return &chainHandler{log.FuncHandler(fn), next, name}
+}
+
+type chainHandler struct {
+	log.Handler
+	next log.Handler
+	name string
+}
+
+func (h *chainHandler) String() string {
+	return h.name
+}
+
+func (h *chainHandler) Flush() error {
+	return log.FlushHandler(h.next)
+}
+
+func (h *chainHandler) Close() error {
+	return log.CloseHandler(h.next)
+}
+
+func (h *chainHandler) Unwrap() []log.Handler {
+	return []log.Handler{h.next}
+}
@@ -0,0 +1,268 @@
+package log
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"time"
+)
+
+// FieldType tells how a Field's value is stored.
+type FieldType uint8
+
+const (
+	UnknownType FieldType = iota
+	StringType
+	IntType
+	UintType
+	FloatType
+	BoolType
+	DurationType
+	TimeType
+	ErrorType
+	AnyType
+)
+
+// A Field is a key/value pair whose value is stored without boxing it
+// in an interface{}. Build fields with String, Int, Dur, Err, Any and
+// friends and pass them to a Logger anywhere a key/value pair may go:
+//
+//     logger.Info("request done", log.String("path", path), log.Dur("took", d), "user", u)
+//
+// Formatters encode fields without reflection. Fields are written after
+// the record's loose key/value pairs.
+//
+// Passing a Field through the ...interface{} methods boxes it like any
+// other value. LogFields takes fields directly and is the path to use
+// where allocations matter:
+//
+//     if logger.Enabled(log.LvlDebug) {
+//         logger.LogFields(ctx, log.LvlDebug, "cache miss", log.String("key", k))
+//     }
+type Field struct {
+	Key       string
+	Type      FieldType
+	Integer   int64
+	String    string
+	Interface interface{}
+}
+
+// String returns a Field holding a string.
+func String(key, value string) Field {
+	return Field{Key: key, Type: StringType, String: value}
+}
+
+// Int returns a Field holding an int.
+func Int(key string, value int) Field {
+	return Field{Key: key, Type: IntType, Integer: int64(value)}
+}
+
+// Int64 returns a Field holding an int64.
+func Int64(key string, value int64) Field {
+	return Field{Key: key, Type: IntType, Integer: value}
+}
+
+// Uint64 returns a Field holding a uint64.
+func Uint64(key string, value uint64) Field {
+	return Field{Key: key, Type: UintType, Integer: int64(value)}
+}
+
+// Float64 returns a Field holding a float64.
+func Float64(key string, value float64) Field {
+	return Field{Key: key, Type: FloatType, Integer: int64(math.Float64bits(value))}
+}
+
+// Bool returns a Field holding a bool.
+func Bool(key string, value bool) Field {
+	var i int64
+	if value {
+		i = 1
+	}
+	return Field{Key: key, Type: BoolType, Integer: i}
+}
+
+// Dur returns a Field holding a time.Duration.
+func Dur(key string, value time.Duration) Field {
+	return Field{Key: key, Type: DurationType, Integer: int64(value)}
+}
+
+// Time returns a Field holding a time.Time.
+func Time(key string, value time.Time) Field {
+	return Field{Key: key, Type: TimeType, Integer: value.UnixNano(), Interface: value.Location()}
+}
+
+// Err returns a Field holding an error under the key "error".
+func Err(err error) Field {
+	return NamedErr(errorKey, err)
+}
+
+// NamedErr returns a Field holding an error under the given key.
+func NamedErr(key string, err error) Field {
+	return Field{Key: key, Type: ErrorType, Interface: err}
+}
+
+// Any returns a Field holding an arbitrary value, choosing a typed
+// representation when the value's type allows it.
+func Any(key string, value interface{}) Field {
+	switch v := value.(type) {
+	case string:
+		return String(key, v)
+	case int:
+		return Int(key, v)
+	case int64:
+		return Int64(key, v)
+	case uint64:
+		return Uint64(key, v)
+	case float64:
+		return Float64(key, v)
+	case bool:
+		return Bool(key, v)
+	case time.Duration:
+		return Dur(key, v)
+	case time.Time:
+		return Time(key, v)
+	case error:
+		return NamedErr(key, v)
+	}
+	return Field{Key: key, Type: AnyType, Interface: value}
+}
+
+// Value returns the field's value as an interface{}. It allocates for
+// most types; formatters use the typed members instead.
+func (f Field) Value() interface{} {
+	switch f.Type {
+	case StringType:
+		return f.String
+	case IntType:
+		return f.Integer
+	case UintType:
+		return uint64(f.Integer)
+	case FloatType:
+		return math.Float64frombits(uint64(f.Integer))
+	case BoolType:
+		return f.Integer == 1
+	case DurationType:
+		return time.Duration(f.Integer)
+	case TimeType:
+		return f.time()
+	default:
+		return f.Interface
+	}
+}
+
+func (f Field) time() time.Time {
+	t := time.Unix(0, f.Integer)
+	if loc, ok := f.Interface.(*time.Location); ok && loc != nil {
+		t = t.In(loc)
+	}
+	return t
+}
+
+// appendLogfmtValue writes the field's value the way formatLogfmtValue
+// would, without boxing typed values.
+func (f Field) appendLogfmtValue(buf *bytes.Buffer) {
+	var scratch [64]byte
+	switch f.Type {
+	case StringType:
+		buf.WriteString(escapeString(f.String))
+	case IntType:
+		buf.Write(strconv.AppendInt(scratch[:0], f.Integer, 10))
+	case UintType:
+		buf.Write(strconv.AppendUint(scratch[:0], uint64(f.Integer), 10))
+	case FloatType:
+		buf.Write(strconv.AppendFloat(scratch[:0], math.Float64frombits(uint64(f.Integer)), floatFormat, 3, 64))
+	case BoolType:
+		buf.Write(strconv.AppendBool(scratch[:0], f.Integer == 1))
+	case DurationType:
+		buf.WriteString(escapeString(time.Duration(f.Integer).String()))
+	case TimeType:
+		buf.WriteByte('"')
+		buf.Write(f.time().AppendFormat(scratch[:0], timeFormat))
+		buf.WriteByte('"')
+	case ErrorType:
+		if f.Interface == nil {
+			buf.WriteString("nil")
+			return
+		}
+		buf.WriteString(escapeString(f.Interface.(error).Error()))
+	default:
+		buf.WriteString(formatLogfmtValue(f.Interface))
+	}
+}
+
+// appendJSONValue writes the field's value the way JsonFormatEx writes
+// loose values, without boxing typed values.
+func (f Field) appendJSONValue(buf *bytes.Buffer) {
+	var scratch [64]byte
+	switch f.Type {
+	case StringType:
+		appendJSONString(buf, f.String)
+	case IntType:
+		buf.Write(strconv.AppendInt(scratch[:0], f.Integer, 10))
+	case UintType:
+		buf.Write(strconv.AppendUint(scratch[:0], uint64(f.Integer), 10))
+	case FloatType:
+		appendJSONFloat(buf, math.Float64frombits(uint64(f.Integer)), 64)
+	case BoolType:
+		buf.Write(strconv.AppendBool(scratch[:0], f.Integer == 1))
+	case DurationType:
+		appendJSONString(buf, time.Duration(f.Integer).String())
+	case TimeType:
+		buf.WriteByte('"')
+		buf.Write(f.time().AppendFormat(scratch[:0], timeFormat))
+		buf.WriteByte('"')
+	case ErrorType:
+		if f.Interface == nil {
+			buf.WriteString("null")
+			return
+		}
+		appendJSONString(buf, f.Interface.(error).Error())
+	default:
+		appendJSONValue(buf, f.Interface)
+	}
+}
+
+// splitFields separates typed Fields from loose key/value pairs. A Field
+// stands in for a whole pair, so it may only appear where a key could.
+// When there are no Fields, args is returned unchanged.
+func splitFields(args []interface{}) ([]interface{}, []Field) {
+	n := 0
+	for i := 0; i < len(args); i++ {
+		if _, ok := args[i].(Field); ok {
+			n++
+			continue
+		}
+		i++ // skip the value
+	}
+	if n == 0 {
+		return args, nil
+	}
+
+	kvs := make([]interface{}, 0, len(args)-n)
+	fields := make([]Field, 0, n)
+	for i := 0; i < len(args); i++ {
+		if f, ok := args[i].(Field); ok {
+			fields = append(fields, f)
+			continue
+		}
+		kvs = append(kvs, args[i])
+		if i+1 < len(args) {
+			i++
+			kvs = append(kvs, args[i])
+		}
+	}
+	return kvs, fields
+}
+
+// newFields returns prefix followed by suffix, sharing prefix when there
+// is nothing to add. The result's capacity is capped so that appending
+// to it never writes into prefix.
+func newFields(prefix, suffix []Field) []Field {
+	if len(suffix) == 0 {
+		return prefix[:len(prefix):len(prefix)]
+	}
+	fields := make([]Field, len(prefix)+len(suffix))
+	n := copy(fields, prefix)
+	copy(fields[n:], suffix)
+	return fields
+}
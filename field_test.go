@@ -0,0 +1,114 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFieldsLogfmt(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	l := New(String("svc", "api"))
+	l.SetHandler(StreamHandler(&buf, FormatFunc(func(r *Record) []byte {
+		b := &bytes.Buffer{}
+		kvaluesfmt(b, r.KeyValues, r.Fields, 0, false)
+		return b.Bytes()
+	})))
+
+	l.Info("msg", Int("n", -3), "loose", 1, Uint64("u", 7), Float64("f", 0.5), Bool("ok", true),
+		Dur("took", 1500*time.Millisecond), Err(errors.New("boom boom")), String("s", "a b"),
+		Time("at", time.Date(2026, 10, 17, 13, 4, 5, 0, time.UTC)), Any("any", []int{1}))
+
+	// loose pairs come first
+	exp := `loose=1 svc=api n=-3 u=7 f=0.500 ok=true took=1.5s error="boom boom" s="a b" at="2026-10-17 13:04:05" any=[1]` + "\n"
+	if got := buf.String(); got != exp {
+		t.Fatalf("got  %s\nexpected %s", got, exp)
+	}
+}
+
+func TestFieldsJson(t *testing.T) {
+	t.Parallel()
+
+	l, buf := testFormatter(JsonFormat())
+	l.Info("msg", Int("n", 3), String("s", "v"), Bool("b", true), Err(nil))
+
+	var v map[string]interface{}
+	if err := json.NewDecoder(buf).Decode(&v); err != nil {
+		t.Fatalf("Error decoding JSON: %v", err)
+	}
+	if v["n"] != float64(3) || v["s"] != "v" || v["b"] != true || v["error"] != nil {
+		t.Fatalf("unexpected fields in %v", v)
+	}
+}
+
+func TestSplitFields(t *testing.T) {
+	t.Parallel()
+
+	kvs, fs := splitFields([]interface{}{"a", 1, Int("b", 2), "c", String("not", "a key")})
+	if len(kvs) != 4 || kvs[0] != "a" || kvs[2] != "c" {
+		t.Fatalf("got loose pairs %v", kvs)
+	}
+	if len(fs) != 1 || fs[0].Key != "b" {
+		t.Fatalf("got fields %v", fs)
+	}
+
+	args := []interface{}{"a", 1}
+	if kvs, fs := splitFields(args); &kvs[0] != &args[0] || fs != nil {
+		t.Fatalf("expected args to be returned unchanged")
+	}
+}
+
+func TestLazyField(t *testing.T) {
+	t.Parallel()
+
+	x := 1
+	l, _, r := testLogger()
+	l = l.New(Any("x", Lazy{func() int { return x }}))
+
+	l.Info("1")
+	if got := r.Fields[0].Value(); got != int64(1) {
+		t.Fatalf("Lazy field not evaluated, got %v", got)
+	}
+	x = 2
+	l.Info("2")
+	if got := r.Fields[0].Value(); got != int64(2) {
+		t.Fatalf("Lazy field evaluated only once, got %v", got)
+	}
+}
+
+func TestFieldsAllocs(t *testing.T) {
+	l := New()
+	l.SetHandler(DiscardHandler())
+	loose := testing.AllocsPerRun(100, func() {
+		l.Info("Go fast.", "string", "four!", "int", 1000, "duration", time.Second)
+	})
+	fields := testing.AllocsPerRun(100, func() {
+		l.LogFields(nil, LvlInfo, "Go fast.", String("string", "four!"), Int("int", 1000), Dur("duration", time.Second))
+	})
+	if fields >= loose {
+		t.Fatalf("fields allocate %v times a record, key/value pairs %v", fields, loose)
+	}
+}
+
+func TestFieldsReused(t *testing.T) {
+	t.Parallel()
+
+	recs := make(chan *Record, 2)
+	l := New(String("svc", "api"))
+	l.SetHandler(ChannelHandler(recs))
+	l.LogFields(nil, LvlInfo, "first", Int("n", 1))
+	l.LogFields(nil, LvlInfo, "second", Int("n", 2), String("x", "y"))
+
+	// the pool reuses the fields of the first record, but not of its clone
+	first := <-recs
+	if len(first.Fields) != 2 || first.Fields[0].String != "api" || first.Fields[1].Integer != 1 {
+		t.Fatalf("first record's fields changed: %+v", first.Fields)
+	}
+	if second := <-recs; len(second.Fields) != 3 || second.Fields[1].Integer != 2 {
+		t.Fatalf("unexpected fields %+v", second.Fields)
+	}
+}
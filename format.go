@@ -7,8 +7,6 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
-	"strings"
-	"sync"
 	"time"
 )
 
@@ -35,6 +33,45 @@ func (f formatFunc) Format(r *Record) []byte {
 	return f(r)
 }
 
+// A BufferFormat is a Format which can write a record into a buffer
+// supplied by the caller. StreamHandler and the network handlers format
+// into buffers taken from the BytesBufferPool, so a BufferFormat costs
+// no allocation per record. All built-in formats are BufferFormats.
+type BufferFormat interface {
+	Format
+	FormatBuffer(buf *bytes.Buffer, r *Record)
+}
+
+// BufferFormatFunc returns a new BufferFormat object which uses the
+// given function to append a formatted record to buf.
+func BufferFormatFunc(f func(buf *bytes.Buffer, r *Record)) BufferFormat {
+	return bufferFormatFunc(f)
+}
+
+type bufferFormatFunc func(buf *bytes.Buffer, r *Record)
+
+func (f bufferFormatFunc) FormatBuffer(buf *bytes.Buffer, r *Record) {
+	f(buf, r)
+}
+
+// Format returns a copy of the formatted record, which the caller owns.
+func (f bufferFormatFunc) Format(r *Record) []byte {
+	buf := getBuffer()
+	f(buf, r)
+	b := append([]byte(nil), buf.Bytes()...)
+	putBuffer(buf)
+	return b
+}
+
+// formatBuffer appends r formatted by fmtr to buf.
+func formatBuffer(buf *bytes.Buffer, fmtr Format, r *Record) {
+	if bf, ok := fmtr.(BufferFormat); ok {
+		bf.FormatBuffer(buf, r)
+		return
+	}
+	buf.Write(fmtr.Format(r))
+}
+
 // TerminalFormat formats log records optimized for human readability on
 // a terminal with color-coded level output and terser human friendly timestamp.
 // This format should only be used for interactive programs or while developing.
@@ -45,38 +82,69 @@ func (f formatFunc) Format(r *Record) []byte {
 //
 //     [May 16 20:58:45] [DBUG] remove route ns=haproxy addr=127.0.0.1:50002
 //
-func TerminalFormat() Format {
-	return FormatFunc(func(r *Record) []byte {
-		var color = 0
-		switch r.Level {
-		case LvlFatal:
-			color = 35
-		case LvlError:
-			color = 31
-		case LvlWarn:
-			color = 33
-		case LvlInfo:
-			color = 32
-		case LvlDebug:
-			color = 36
-		case LvlTrace:
-			color = 30
-		}
-
-		b := &bytes.Buffer{}
-		lvl := strings.ToUpper(r.Level.String())
+// The time, level and caller are written without keys; RecordKeyNames
+// can still leave them out or reorder them. The time is written as
+// termTimeFormat unless SetTimeFormat says otherwise.
+func TerminalFormat(opts ...FormatOptions) Format {
+	o := newFormatOptions(termTimeFormat, opts)
+	return BufferFormatFunc(func(b *bytes.Buffer, r *Record) {
+		color := r.Level.color()
+		order := terminalKeyOrder
 		if color > 0 {
-			fmt.Fprintf(b, "\x1b[%dm%s\x1b[0m [%s][%s] %s=%s ", color, lvl, r.Time.Format(termTimeFormat), r.Call.String(), r.KeyNames.Msg, r.Msg)
-		} else {
-			fmt.Fprintf(b, "[%s][%s][%s] %s=%s ", lvl, r.Call.String(), r.Time.Format(termTimeFormat), r.KeyNames.Msg, r.Msg)
+			order = terminalColorKeyOrder
 		}
 
-		if r.Ctx != nil && r.Ctx.Value(requestID) != nil {
-			requestID := r.Ctx.Value(requestID).(string)
-			if len(requestID) > 0 {
-				fmt.Fprintf(b, "%s=%s ", r.KeyNames.RequestID, requestID)
+		// bracketed fields are written back to back, the others are
+		// separated by spaces
+		span := recordTrace(r)
+		n, bracketed := 0, false
+		sep := func(bracket bool) {
+			if n > 0 && !(bracket && bracketed) {
+				b.WriteByte(' ')
+			}
+			n++
+			bracketed = bracket
+		}
+		for _, key := range r.KeyNames.order(order) {
+			switch key {
+			case KeyLevel:
+				if color > 0 {
+					sep(false)
+					fmt.Fprintf(b, "\x1b[%dm%s\x1b[0m", color, r.Level.short())
+				} else {
+					sep(true)
+					b.WriteByte('[')
+					b.WriteString(r.Level.short())
+					b.WriteByte(']')
+				}
+			case KeyTime:
+				var scratch [64]byte
+				sep(true)
+				b.WriteByte('[')
+				b.Write(o.appendTime(scratch[:0], r.Time))
+				b.WriteByte(']')
+			case KeyCall:
+				sep(true)
+				b.WriteByte('[')
+				b.WriteString(r.Call.String())
+				b.WriteByte(']')
+			case KeyMsg:
+				sep(false)
+				b.WriteString(r.KeyNames.Msg)
+				b.WriteByte('=')
+				b.WriteString(r.Msg)
+			case KeyRequestID, KeySpanID, KeyParentID:
+				if id := traceID(span, key); id != "" {
+					sep(false)
+					b.WriteString(r.KeyNames.name(key))
+					b.WriteByte('=')
+					b.WriteString(id)
+				}
 			}
 		}
+		if n > 0 {
+			b.WriteByte(' ')
+		}
 
 		// try to justify the log output for short messages
 		// 此处控制的是msg和后续kvalues中间的' '
@@ -85,18 +153,24 @@ func TerminalFormat() Format {
 		//}
 
 		// print the keys kvaluesfmt style
-		kvaluesfmt(b, r.KeyValues, color)
-		return b.Bytes()
+		kvaluesfmt(b, r.KeyValues, r.Fields, color, false)
 	})
 }
 
-// LogfmtFormat prints records in kvaluesfmt format, an easy machine-parseable but human-readable
-// format for key/value pairs.
+// LogfmtFormat prints records in logfmt, an easy machine-parseable but
+// human-readable format for key/value pairs:
+//
+//     time="2026-10-17 21:04:05.12" level=info location=main.go:12 msg=started port=80
+//
+// Keys are sanitized, and values quoted and escaped, as logfmt requires,
+// so the output can be read by any logfmt parser, including ParseLogfmt.
 //
 // For more details see: https://pkg.go.dev/github.com/kr/logfmt
 //
-func LogfmtFormat() Format {
-	return FormatFunc(func(r *Record) []byte {
+// The time is written as timeFormat unless SetTimeFormat says otherwise.
+func LogfmtFormat(opts ...FormatOptions) Format {
+	o := newFormatOptions(timeFormat, opts)
+	return BufferFormatFunc(func(buf *bytes.Buffer, r *Record) {
 		var caller string
 		if r.CustomCaller == "" {
 			caller = r.Call.String()
@@ -104,24 +178,44 @@ func LogfmtFormat() Format {
 			caller = r.CustomCaller
 		}
 
-		common := []interface{}{r.KeyNames.Time, r.Time, r.KeyNames.Level, r.Level, r.KeyNames.Call, caller, r.KeyNames.Msg, r.Msg}
-
-		if r.Ctx != nil && r.Ctx.Value(requestID) != nil {
-
-			requestID := r.Ctx.Value(requestID).(string)
-			if len(requestID) > 0 {
-				common = append(common, r.KeyNames.RequestID, requestID)
+		span := recordTrace(r)
+		n := 0
+		for _, key := range r.KeyNames.order(logfmtKeyOrder) {
+			var scratch [64]byte
+			var v string
+			switch key {
+			case KeyTime:
+				v = string(o.appendTime(scratch[:0], r.Time))
+			case KeyLevel:
+				v = r.Level.String()
+			case KeyCall:
+				v = caller
+			case KeyMsg:
+				v = r.Msg
+			case KeyRequestID, KeySpanID, KeyParentID:
+				if v = traceID(span, key); v == "" {
+					continue
+				}
+			default:
+				continue
+			}
+			if n > 0 {
+				buf.WriteByte(' ')
 			}
+			n++
+			appendLogfmtKey(buf, r.KeyNames.name(key))
+			buf.WriteByte('=')
+			appendLogfmtString(buf, v)
 		}
-		buf := &bytes.Buffer{}
-		kvaluesfmt(buf, append(common, r.KeyValues...), 0)
-		return buf.Bytes()
+		kvaluesfmt(buf, r.KeyValues, r.Fields, 0, n > 0)
 	})
 }
 
-func kvaluesfmt(buf *bytes.Buffer, KeyValues []interface{}, color int) {
+// kvaluesfmt writes the key/value pairs and fields of a record and ends
+// the line. If sep is true a space is written before the first pair.
+func kvaluesfmt(buf *bytes.Buffer, KeyValues []interface{}, fields []Field, color int, sep bool) {
 	for i := 0; i < len(KeyValues); i += 2 {
-		if i != 0 {
+		if i != 0 || sep {
 			buf.WriteByte(' ')
 		}
 
@@ -129,83 +223,36 @@ func kvaluesfmt(buf *bytes.Buffer, KeyValues []interface{}, color int) {
 		v := formatLogfmtValue(KeyValues[i+1])
 		if !ok {
 			//k, v = errorKey, formatLogfmtValue(k)
-			k, v = errorKey, fmt.Sprintf("%+v is not a string key", KeyValues[i+1])
+			k, v = errorKey, escapeString(fmt.Sprintf("%+v is not a string key", KeyValues[i]))
 		}
 
-		// XXX: we should probably check that all of your key bytes aren't invalid
 		if color > 0 {
-			fmt.Fprintf(buf, "\x1b[%dm%s\x1b[0m=%s", color, k, v)
+			fmt.Fprintf(buf, "\x1b[%dm", color)
+			appendLogfmtKey(buf, k)
+			buf.WriteString("\x1b[0m=")
 		} else {
-			if i < 5 {
-				buf.WriteByte('[')
-			} else {
-				buf.WriteString(k)
-				buf.WriteByte('=')
-			}
-			buf.WriteString(v)
-			if i < 5 {
-				buf.WriteByte(']')
-			}
-		}
-	}
-
-	buf.WriteByte('\n')
-}
-
-// JsonFormat formats log records as JSON objects separated by newlines.
-// It is the equivalent of JsonFormatEx(false, true).
-func JsonFormat() Format {
-	return JsonFormatEx(false, true)
-}
-
-// JsonFormatEx formats log records as JSON objects. If pretty is true,
-// records will be pretty-printed. If lineSeparated is true, records
-// will be logged with a new line between each record.
-func JsonFormatEx(pretty, lineSeparated bool) Format {
-	jsonMarshal := json.Marshal
-	if pretty {
-		jsonMarshal = func(v interface{}) ([]byte, error) {
-			return json.MarshalIndent(v, "", "    ")
+			appendLogfmtKey(buf, k)
+			buf.WriteByte('=')
 		}
+		buf.WriteString(v)
 	}
 
-	return FormatFunc(func(r *Record) []byte {
-		props := make(map[string]interface{})
-
-		props[r.KeyNames.Time] = r.Time
-		props[r.KeyNames.Level] = r.Level.String()
-		props[r.KeyNames.Msg] = r.Msg
-
-		if r.Ctx != nil && r.Ctx.Value(requestID) != nil {
-
-			requestID := r.Ctx.Value(requestID).(string)
-			if len(requestID) > 0 {
-				props[r.KeyNames.RequestID] = requestID
-			}
-		}
-
-		for i := 0; i < len(r.KeyValues); i += 2 {
-			k, ok := r.KeyValues[i].(string)
-			if !ok {
-				props[errorKey] = fmt.Sprintf("%+v is not a string key", r.KeyValues[i])
-			}
-			props[k] = formatJsonValue(r.KeyValues[i+1])
-		}
-
-		b, err := jsonMarshal(props)
-		if err != nil {
-			b, _ = jsonMarshal(map[string]string{
-				errorKey: err.Error(),
-			})
-			return b
+	for i, f := range fields {
+		if i != 0 || len(KeyValues) != 0 || sep {
+			buf.WriteByte(' ')
 		}
-
-		if lineSeparated {
-			b = append(b, '\n')
+		if color > 0 {
+			fmt.Fprintf(buf, "\x1b[%dm", color)
+			appendLogfmtKey(buf, f.Key)
+			buf.WriteString("\x1b[0m=")
+		} else {
+			appendLogfmtKey(buf, f.Key)
+			buf.WriteByte('=')
 		}
+		f.appendLogfmtValue(buf)
+	}
 
-		return b
-	})
+	buf.WriteByte('\n')
 }
 
 func formatShared(value interface{}) (result interface{}) {
@@ -234,16 +281,6 @@ func formatShared(value interface{}) (result interface{}) {
 	}
 }
 
-func formatJsonValue(value interface{}) interface{} {
-	value = formatShared(value)
-	switch value.(type) {
-	case int, int8, int16, int32, int64, float32, float64, uint, uint8, uint16, uint32, uint64, string:
-		return value
-	default:
-		return fmt.Sprintf("%+v", value)
-	}
-}
-
 // formatValue formats a value for serialization
 func formatLogfmtValue(value interface{}) string {
 	if value == nil {
@@ -251,10 +288,8 @@ func formatLogfmtValue(value interface{}) string {
 	}
 
 	if t, ok := value.(time.Time); ok {
-		// Performance optimization: No need for escaping since the provided
-		// timeFormat doesn't have any escape characters, and escaping is
-		// expensive.
-		return t.Format(timeFormat)
+		// timeFormat has a space, so the time is always quoted
+		return `"` + t.Format(timeFormat) + `"`
 	}
 	value = formatShared(value)
 	switch v := value.(type) {
@@ -273,53 +308,6 @@ func formatLogfmtValue(value interface{}) string {
 	}
 }
 
-var stringBufPool = sync.Pool{
-	New: func() interface{} { return new(bytes.Buffer) },
-}
-
-func escapeString(s string) string {
-	needsQuotes := false
-	needsEscape := false
-	for _, r := range s {
-		if r <= ' ' || r == '=' || r == '"' {
-			needsQuotes = true
-		}
-		if r == '\\' || r == '"' || r == '\n' || r == '\r' || r == '\t' {
-			needsEscape = true
-		}
-	}
-	if !needsEscape && !needsQuotes {
-		return s
-	}
-	e := stringBufPool.Get().(*bytes.Buffer)
-	e.WriteByte('"')
-	for _, r := range s {
-		switch r {
-		case '\\', '"':
-			e.WriteByte('\\')
-			e.WriteByte(byte(r))
-		case '\n':
-			e.WriteString("\\n")
-		case '\r':
-			e.WriteString("\\r")
-		case '\t':
-			e.WriteString("\\t")
-		default:
-			e.WriteRune(r)
-		}
-	}
-	e.WriteByte('"')
-	var ret string
-	if needsQuotes {
-		ret = e.String()
-	} else {
-		ret = string(e.Bytes()[1 : e.Len()-1])
-	}
-	e.Reset()
-	stringBufPool.Put(e)
-	return ret
-}
-
 // JSON is a helper function, following is its function code.
 //
 //  data, _ := json.Marshal(v)
@@ -1,6 +1,7 @@
 package log
 
 import (
+	"errors"
 	"fmt"
 	"github.com/go-stack/stack"
 	"github.com/wuzuoliang/log/lumberjack.v2"
@@ -8,6 +9,8 @@ import (
 	"net"
 	"os"
 	"reflect"
+	"runtime"
+	"strings"
 	"sync"
 	"sync/atomic"
 )
@@ -16,6 +19,8 @@ import (
 // The Handler interface defines where and how log records are written.
 // Handlers are composable, providing you great flexibility in combining
 // them to achieve the logging structure that suits your applications.
+//
+// The record given to Log is only valid until Log returns, see Record.
 type Handler interface {
 	Log(r *Record) error
 }
@@ -32,37 +37,188 @@ func (h funcHandler) Log(r *Record) error {
 	return h(r)
 }
 
+// A Closer is a Handler which holds resources, such as open files or
+// network connections, that must be released when logging is finished.
+// Handlers which wrap other handlers implement Closer as well and
+// propagate the call to each of their children.
+type Closer interface {
+	Close() error
+}
+
+// A Flusher is a Handler which may buffer records before they reach
+// their final destination. Flush blocks until every record accepted so
+// far has been handed off to the underlying writer and that writer has
+// been flushed or synced.
+type Flusher interface {
+	Flush() error
+}
+
+// CloseHandler closes h if it implements Closer. It is safe to call on
+// any Handler.
+func CloseHandler(h Handler) error {
+	if c, ok := h.(Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// FlushHandler flushes h if it implements Flusher. It is safe to call on
+// any Handler.
+func FlushHandler(h Handler) error {
+	if f, ok := h.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// A Wrapper is a Handler which passes records on to other handlers.
+// Unwrap returns them, so that tools such as an admin endpoint can walk
+// a handler tree.
+type Wrapper interface {
+	Unwrap() []Handler
+}
+
+// UnwrapHandler returns the handlers h passes records to, or nil if h
+// doesn't implement Wrapper.
+func UnwrapHandler(h Handler) []Handler {
+	if w, ok := h.(Wrapper); ok {
+		return w.Unwrap()
+	}
+	return nil
+}
+
+// closeHandlers closes every handler in hs, returning the first error.
+func closeHandlers(hs ...Handler) (err error) {
+	for _, h := range hs {
+		if errClose := CloseHandler(h); err == nil && errClose != nil {
+			err = errClose
+		}
+	}
+	return err
+}
+
+// flushHandlers flushes every handler in hs, returning the first error.
+func flushHandlers(hs ...Handler) (err error) {
+	for _, h := range hs {
+		if errFlush := FlushHandler(h); err == nil && errFlush != nil {
+			err = errFlush
+		}
+	}
+	return err
+}
+
+// wrapHandler returns a Handler that logs records with the given
+// function and forwards Close and Flush to the handlers it wraps.
+func wrapHandler(fn func(r *Record) error, hs ...Handler) Handler {
+	return &wrappingHandler{fn, hs}
+}
+
+type wrappingHandler struct {
+	fn       func(r *Record) error
+	children []Handler
+}
+
+func (h *wrappingHandler) Log(r *Record) error {
+	return h.fn(r)
+}
+
+func (h *wrappingHandler) Close() error {
+	return closeHandlers(h.children...)
+}
+
+func (h *wrappingHandler) Flush() error {
+	return flushHandlers(h.children...)
+}
+
+func (h *wrappingHandler) Unwrap() []Handler {
+	return h.children
+}
+
+// String names the handler after the function that built it, such as
+// "log.LvlFilterHandler".
+func (h *wrappingHandler) String() string {
+	return funcName(h.fn)
+}
+
+// funcName returns the package qualified name of the function which
+// defines the closure fn.
+func funcName(fn interface{}) string {
+	f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer())
+	if f == nil {
+		return "?"
+	}
+	name := f.Name()
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		name = name[i+1:]
+	}
+	// drop the closure suffixes, e.g. ".func1"
+	for {
+		i := strings.LastIndex(name, ".func")
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return name
+}
+
 // LazyHandler writes all values to the wrapped handler after evaluating
 // any lazy functions in the record's context. It is already wrapped
 // around StreamHandler and SyslogHandler in this library, you'll only need
 // it if you write your own Handler.
 func LazyHandler(h Handler) Handler {
-	return FuncHandler(func(r *Record) error {
-		// go through the values (odd indices) and reassign
-		// the values of any lazy fn to the result of its execution
-		hadErr := false
-		for i := 1; i < len(r.KeyValues); i += 2 {
-			lz, ok := r.KeyValues[i].(Lazy)
-			if ok {
-				v, err := evaluateLazy(lz)
-				if err != nil {
-					hadErr = true
-					r.KeyValues[i] = err
-				} else {
-					if cs, ok := v.(stack.CallStack); ok {
-						v = cs.TrimBelow(r.Call).TrimRuntime()
-					}
-					r.KeyValues[i] = v
+	return wrapHandler(func(r *Record) error {
+		evaluateLazies(r)
+		return h.Log(r)
+	}, h)
+}
+
+// evaluateLazies goes through the values (odd indices) and reassigns
+// the values of any lazy fn to the result of its execution.
+func evaluateLazies(r *Record) {
+	hadErr := false
+	for i := 1; i < len(r.KeyValues); i += 2 {
+		lz, ok := r.KeyValues[i].(Lazy)
+		if ok {
+			v, err := evaluateLazy(lz)
+			if err != nil {
+				hadErr = true
+				r.KeyValues[i] = err
+			} else {
+				if cs, ok := v.(stack.CallStack); ok {
+					v = cs.TrimBelow(r.Call).TrimRuntime()
 				}
+				r.KeyValues[i] = v
 			}
 		}
+	}
 
-		if hadErr {
-			r.KeyValues = append(r.KeyValues, errorKey, "bad lazy")
+	copied := false
+	for i, f := range r.Fields {
+		lz, ok := f.Interface.(Lazy)
+		if !ok || f.Type != AnyType {
+			continue
 		}
+		if !copied {
+			// the slice may be shared with the logger's own fields
+			r.Fields = append([]Field(nil), r.Fields...)
+			copied = true
+		}
+		v, err := evaluateLazy(lz)
+		if err != nil {
+			hadErr = true
+			r.Fields[i] = NamedErr(f.Key, err)
+		} else {
+			if cs, ok := v.(stack.CallStack); ok {
+				v = cs.TrimBelow(r.Call).TrimRuntime()
+			}
+			r.Fields[i] = Any(f.Key, v)
+		}
+	}
 
-		return h.Log(r)
-	})
+	if hadErr {
+		r.KeyValues = append(r.KeyValues, errorKey, "bad lazy")
+	}
 }
 
 // StreamHandler writes log records to an io.Writer
@@ -72,24 +228,76 @@ func LazyHandler(h Handler) Handler {
 //
 // StreamHandler wraps itself with LazyHandler and SyncHandler
 // to evaluate Lazy objects and perform safe concurrent writes.
+//
+// Flushing a StreamHandler flushes or syncs the writer if it has a
+// Flush() error or Sync() error method. Closing it does not close the
+// writer; use FileHandler or NetHandler if the handler should own it.
 func StreamHandler(wr io.Writer, fmtr Format) Handler {
-	h := FuncHandler(func(r *Record) error {
-		_, err := wr.Write(fmtr.Format(r))
-		return err
-	})
-	return LazyHandler(SyncHandler(h))
+	return LazyHandler(SyncHandler(&streamHandler{wr, fmtr}))
+}
+
+type streamHandler struct {
+	wr   io.Writer
+	fmtr Format
+}
+
+func (h *streamHandler) Log(r *Record) error {
+	buf := getBuffer()
+	formatBuffer(buf, h.fmtr, r)
+	_, err := h.wr.Write(buf.Bytes())
+	putBuffer(buf)
+	return err
+}
+
+func (h *streamHandler) Flush() error {
+	switch w := h.wr.(type) {
+	case interface{ Flush() error }:
+		return w.Flush()
+	case *os.File:
+		// terminals and pipes can't be synced
+		if fi, err := w.Stat(); err != nil || !fi.Mode().IsRegular() {
+			return nil
+		}
+		return w.Sync()
+	case interface{ Sync() error }:
+		return w.Sync()
+	}
+	return nil
 }
 
 // SyncHandler can be wrapped around a handler to guarantee that
 // only a single Log operation can proceed at a time. It's necessary
-// for thread-safe concurrent writes.
+// for thread-safe concurrent writes. Flush and Close hold the same
+// lock, so they never interleave with a write in progress.
 func SyncHandler(h Handler) Handler {
-	var mu sync.Mutex
-	return FuncHandler(func(r *Record) error {
-		mu.Lock()
-		defer mu.Unlock()
-		return h.Log(r)
-	})
+	return &syncHandler{h: h}
+}
+
+type syncHandler struct {
+	mu sync.Mutex
+	h  Handler
+}
+
+func (h *syncHandler) Log(r *Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.h.Log(r)
+}
+
+func (h *syncHandler) Flush() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return FlushHandler(h.h)
+}
+
+func (h *syncHandler) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return CloseHandler(h.h)
+}
+
+func (h *syncHandler) Unwrap() []Handler {
+	return []Handler{h.h}
 }
 
 // FileHandler returns a handler which writes log records to the give file
@@ -101,7 +309,7 @@ func FileHandler(path string, fmtr Format) (Handler, error) {
 	if err != nil {
 		return nil, err
 	}
-	return closingHandler{f, StreamHandler(f, fmtr)}, nil
+	return &closingHandler{WriteCloser: f, Handler: StreamHandler(f, fmtr)}, nil
 }
 
 // NetHandler opens a socket to the given address and writes records
@@ -112,44 +320,75 @@ func NetHandler(network, addr string, fmtr Format) (Handler, error) {
 		return nil, err
 	}
 
-	return closingHandler{conn, StreamHandler(conn, fmtr)}, nil
+	return &closingHandler{WriteCloser: conn, Handler: StreamHandler(conn, fmtr)}, nil
 }
 
-// FileHandlerRotate add lumberjack lib
+// FileHandlerRotate add lumberjack lib. The returned handler has a
+// Rotate() error method which forces the file to be rotated.
 func FileHandlerRotate(output string, fmtr Format, options []RotateOptions) (Handler, error) {
-	f := lumberjack.NewLogger(output, lumberjack.DefaultRotateOption())
-	SetDefaultRotateOptions(append(options, SetOutput(&f)))
-	return closingHandler{&f, StreamHandler(&f, fmtr)}, nil
+	f := lumberjack.NewLogger(output, newRotateOptions(options).rotateOption())
+	return &rotatingHandler{
+		closingHandler: &closingHandler{WriteCloser: &f, Handler: StreamHandler(&f, fmtr)},
+		output:         &f,
+	}, nil
 }
 
-// XXX: closingHandler is essentially unused at the moment
-// it's meant for a future time when the Handler interface supports
-// a possible Close() operation
+// rotatingHandler is a closingHandler writing to a lumberjack.Logger.
+type rotatingHandler struct {
+	*closingHandler
+	output *lumberjack.Logger
+}
+
+// Rotate closes the current file and starts a new one.
+func (h *rotatingHandler) Rotate() error {
+	return h.output.Rotate()
+}
+
+// closingHandler owns the io.WriteCloser its handler writes to. Closing
+// it closes the wrapped handler first and then the writer; calling Close
+// more than once is harmless.
 type closingHandler struct {
 	io.WriteCloser
 	Handler
+
+	once sync.Once
+	err  error
+}
+
+func (h *closingHandler) Flush() error {
+	return FlushHandler(h.Handler)
+}
+
+func (h *closingHandler) Unwrap() []Handler {
+	return []Handler{h.Handler}
 }
 
 func (h *closingHandler) Close() error {
-	return h.WriteCloser.Close()
+	h.once.Do(func() {
+		h.err = CloseHandler(h.Handler)
+		if err := h.WriteCloser.Close(); h.err == nil && err != nil {
+			h.err = err
+		}
+	})
+	return h.err
 }
 
 // CallerFileHandler returns a Handler that adds the line number and file of
 // the calling function to the context with key "caller".
 func CallerFileHandler(h Handler) Handler {
-	return FuncHandler(func(r *Record) error {
+	return wrapHandler(func(r *Record) error {
 		r.KeyValues = append(r.KeyValues, "caller", fmt.Sprint(r.Call))
 		return h.Log(r)
-	})
+	}, h)
 }
 
 // CallerFuncHandler returns a Handler that adds the calling function name to
 // the context with key "fn".
 func CallerFuncHandler(h Handler) Handler {
-	return FuncHandler(func(r *Record) error {
+	return wrapHandler(func(r *Record) error {
 		r.KeyValues = append(r.KeyValues, "fn", fmt.Sprintf("%+n", r.Call))
 		return h.Log(r)
-	})
+	}, h)
 }
 
 // CallerStackHandler returns a Handler that adds a stack trace to the context
@@ -158,13 +397,13 @@ func CallerFuncHandler(h Handler) Handler {
 // Each call site is formatted according to format. See the documentation of
 // package github.com/go-stack/stack for the list of supported formats.
 func CallerStackHandler(format string, h Handler) Handler {
-	return FuncHandler(func(r *Record) error {
+	return wrapHandler(func(r *Record) error {
 		s := stack.Trace().TrimBelow(r.Call).TrimRuntime()
 		if len(s) > 0 {
 			r.KeyValues = append(r.KeyValues, "stack", fmt.Sprintf(format, s))
 		}
 		return h.Log(r)
-	})
+	}, h)
 }
 
 // FilterHandler returns a Handler that only writes records to the
@@ -181,12 +420,12 @@ func CallerStackHandler(format string, h Handler) Handler {
 //    }, h))
 //
 func FilterHandler(fn func(r *Record) bool, h Handler) Handler {
-	return FuncHandler(func(r *Record) error {
+	return wrapHandler(func(r *Record) error {
 		if fn(r) {
 			return h.Log(r)
 		}
 		return nil
-	})
+	}, h)
 }
 
 // MatchFilterHandler returns a Handler that only writes records
@@ -212,6 +451,11 @@ func MatchFilterHandler(key string, value interface{}, h Handler) Handler {
 				return r.KeyValues[i+1] == value
 			}
 		}
+		for _, f := range r.Fields {
+			if f.Key == key {
+				return f.Value() == value
+			}
+		}
 		return false
 	}, h)
 }
@@ -225,7 +469,7 @@ func MatchFilterHandler(key string, value interface{}, h Handler) Handler {
 //
 func LvlFilterHandler(maxLvl Level, h Handler) Handler {
 	return FilterHandler(func(r *Record) (pass bool) {
-		return r.Level <= maxLvl
+		return r.Level.Compare(maxLvl) <= 0
 	}, h)
 }
 
@@ -239,13 +483,13 @@ func LvlFilterHandler(maxLvl Level, h Handler) Handler {
 //         log.StderrHandler)
 //
 func MultiHandler(hs ...Handler) Handler {
-	return FuncHandler(func(r *Record) error {
+	return wrapHandler(func(r *Record) error {
 		for _, h := range hs {
 			// what to do about failures?
 			h.Log(r)
 		}
 		return nil
-	})
+	}, hs...)
 }
 
 // A FailoverHandler writes all log records to the first handler
@@ -264,7 +508,7 @@ func MultiHandler(hs ...Handler) Handler {
 // the form "failover_err_{idx}" which explain the error encountered while
 // trying to write to the handlers before them in the list.
 func FailoverHandler(hs ...Handler) Handler {
-	return FuncHandler(func(r *Record) error {
+	return wrapHandler(func(r *Record) error {
 		var err error
 		for i, h := range hs {
 			err = h.Log(r)
@@ -276,15 +520,16 @@ func FailoverHandler(hs ...Handler) Handler {
 		}
 
 		return err
-	})
+	}, hs...)
 }
 
 // ChannelHandler writes all records to the given channel.
 // It blocks if the channel is full. Useful for async processing
-// of log messages, it's used by BufferedHandler.
+// of log messages. The records sent are clones which the receiver
+// owns.
 func ChannelHandler(recs chan<- *Record) Handler {
 	return FuncHandler(func(r *Record) error {
-		recs <- r
+		recs <- r.Clone()
 		return nil
 	})
 }
@@ -294,16 +539,25 @@ func ChannelHandler(recs chan<- *Record) Handler {
 // handler whenever it is available for writing. Since these
 // writes happen asynchronously, all writes to a BufferedHandler
 // never return an error and any errors from the wrapped handler are ignored.
+//
+// BufferedHandler is an AsyncHandler that blocks when the channel is
+// full and never reports its counters; use AsyncHandler directly for
+// other overflow policies. Flush waits until every record queued so far
+// has been written to the wrapped handler. Close drains the channel and
+// then closes the wrapped handler; records logged after Close are
+// rejected with ErrHandlerClosed.
 func BufferedHandler(bufSize int, h Handler) Handler {
-	recs := make(chan *Record, bufSize)
-	go func() {
-		for m := range recs {
-			_ = h.Log(m)
-		}
-	}()
-	return ChannelHandler(recs)
+	return AsyncHandler(h,
+		SetAsyncQueueSize(bufSize),
+		SetAsyncOverflow(OverflowBlock),
+		SetAsyncBatchSize(1),
+		SetAsyncFlushInterval(0),
+		SetAsyncReportInterval(0))
 }
 
+// ErrHandlerClosed is returned by handlers that have been closed.
+var ErrHandlerClosed = errors.New("log: handler closed")
+
 func evaluateLazy(lz Lazy) (interface{}, error) {
 	t := reflect.TypeOf(lz.Fn)
 
@@ -359,6 +613,18 @@ func (h *swapHandler) Get() Handler {
 	return *h.handler.Load().(*Handler)
 }
 
+func (h *swapHandler) Flush() error {
+	return FlushHandler(h.Get())
+}
+
+func (h *swapHandler) Close() error {
+	return CloseHandler(h.Get())
+}
+
+func (h *swapHandler) Unwrap() []Handler {
+	return []Handler{h.Get()}
+}
+
 // Lazy allows you to defer calculation of a logged value that is expensive
 // to compute until it is certain that it must be evaluated with the given filters.
 //
@@ -0,0 +1,208 @@
+// Package httplog logs HTTP requests with the trace ids they carry, on
+// the server side with Handler and on the client side with Transport.
+package httplog
+
+import (
+	"net/http"
+	"time"
+
+	log "github.com/wuzuoliang/log"
+	"github.com/wuzuoliang/log/ext"
+	"github.com/wuzuoliang/log/trace"
+)
+
+// options storage request logging parameters
+type options struct {
+	logger log.Logger                 // 写请求日志的 logger, nil 表示 log.Root()
+	msg    string                     // 请求日志的 msg
+	level  func(status int) log.Level // 由状态码决定日志级别
+	header string                     // 请求 id 所在的 header
+	idLen  int                        // 新请求 id 的随机字节数
+
+	props         []trace.Propagation // Transport 传递 span 的方式
+	logHeaders    bool                // Transport 是否记录请求 header
+	redactHeaders map[string]bool     // 记录时隐藏值的 header, canonical 形式
+	redactQuery   map[string]bool     // 记录时隐藏值的 query 参数
+}
+
+var _defaultOptions = options{
+	msg:    "http request",
+	level:  StatusLevel,
+	header: trace.TraceIDHeaderKey,
+	idLen:  16,
+
+	props: []trace.Propagation{trace.PropagateW3C},
+	redactHeaders: map[string]bool{
+		"Authorization":       true,
+		"Proxy-Authorization": true,
+		"Cookie":              true,
+	},
+}
+
+// Options configures Handler and Transport.
+type Options func(*options)
+
+// SetLogger sets the logger requests are logged with, log.Root() at the
+// time of each request by default.
+func SetLogger(l log.Logger) Options {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// SetMessage sets the message of request records, "http request" by
+// default.
+func SetMessage(msg string) Options {
+	return func(o *options) {
+		o.msg = msg
+	}
+}
+
+// SetLevelFunc sets the function which picks the level of a request's
+// record from its status code, StatusLevel by default.
+func SetLevelFunc(fn func(status int) log.Level) Options {
+	return func(o *options) {
+		if fn == nil {
+			fn = StatusLevel
+		}
+		o.level = fn
+	}
+}
+
+// SetRequestIDHeader sets the header request ids are read from and
+// written to, trace.TraceIDHeaderKey by default.
+func SetRequestIDHeader(header string) Options {
+	return func(o *options) {
+		o.header = header
+	}
+}
+
+// SetIDLength sets how many random bytes new request ids are made of, 16
+// by default, as many as a W3C trace id.
+func SetIDLength(n int) Options {
+	return func(o *options) {
+		o.idLen = n
+	}
+}
+
+// SetPropagation sets how Transport passes the span of a call on to the
+// server, in addition to the request id header; trace.PropagateW3C by
+// default.
+func SetPropagation(props ...trace.Propagation) Options {
+	return func(o *options) {
+		o.props = props
+	}
+}
+
+// SetLogHeaders makes Transport log the headers of each request, with
+// the values of redacted headers replaced.
+func SetLogHeaders(on bool) Options {
+	return func(o *options) {
+		o.logHeaders = on
+	}
+}
+
+// SetRedactHeaders sets the headers whose values Transport never logs,
+// Authorization, Proxy-Authorization and Cookie by default.
+func SetRedactHeaders(headers ...string) Options {
+	return func(o *options) {
+		o.redactHeaders = make(map[string]bool, len(headers))
+		for _, h := range headers {
+			o.redactHeaders[http.CanonicalHeaderKey(h)] = true
+		}
+	}
+}
+
+// SetRedactQuery sets the query parameters whose values Transport never
+// logs; none by default. The password of a URL is never logged.
+func SetRedactQuery(params ...string) Options {
+	return func(o *options) {
+		o.redactQuery = make(map[string]bool, len(params))
+		for _, p := range params {
+			o.redactQuery[p] = true
+		}
+	}
+}
+
+func newOptions(def options, opts []Options) *options {
+	o := def
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(&o)
+	}
+	return &o
+}
+
+func (o *options) getLogger() log.Logger {
+	if o.logger == nil {
+		return log.Root()
+	}
+	return o.logger
+}
+
+// StatusLevel is the default level of request records: LvlError for 5xx
+// status codes, LvlWarn for 4xx and LvlInfo for the others.
+func StatusLevel(status int) log.Level {
+	switch {
+	case status >= 500:
+		return log.LvlError
+	case status >= 400:
+		return log.LvlWarn
+	}
+	return log.LvlInfo
+}
+
+// Handler returns an http.Handler which serves requests with next and
+// logs each of them once it is served:
+//
+//     msg="http request" method=GET path=/users status=200 bytes=512 duration=1.2ms remote_addr=10.0.0.7:51234 user_agent=curl/8.0 request_id=...
+//
+// The request keeps the trace id trace.SpanFromRequest finds, else the
+// id in the request id header, else gets a new one from ext.RandId. The
+// server span of the request, a child of the caller's, is stored in the
+// request's context with trace.NewSpanContext, so that every record
+// logged with it has the ids, and the request id is echoed in the
+// response's request id header.
+func Handler(next http.Handler, opts ...Options) http.Handler {
+	o := newOptions(_defaultOptions, opts)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		span, ok := trace.SpanFromRequest(r)
+		if !ok || span.TraceID == "" {
+			id := r.Header.Get(o.header)
+			if id == "" {
+				id = ext.RandId(o.idLen)
+			}
+			span = trace.Span{TraceID: id, Flags: trace.FlagSampled}
+		}
+		span = span.Child()
+
+		ctx := trace.NewSpanContext(r.Context(), span)
+		r = r.WithContext(ctx)
+		w.Header().Set(o.header, span.TraceID)
+
+		rw := WrapResponseWriter(w)
+		next.ServeHTTP(rw, r)
+
+		status := rw.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		l, level := o.getLogger(), o.level(status)
+		if !l.Enabled(level) {
+			return
+		}
+		l.LogFields(ctx, level, o.msg,
+			log.String("method", r.Method),
+			log.String("path", r.URL.Path),
+			log.Int("status", status),
+			log.Int64("bytes", rw.BytesWritten()),
+			log.Dur("duration", time.Since(start)),
+			log.String("remote_addr", r.RemoteAddr),
+			log.String("user_agent", r.UserAgent()),
+		)
+	})
+}
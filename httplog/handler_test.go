@@ -0,0 +1,133 @@
+package httplog
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	log "github.com/wuzuoliang/log"
+	"github.com/wuzuoliang/log/trace"
+)
+
+func testLogger() (log.Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	l := log.New()
+	l.SetHandler(log.StreamHandler(&buf, log.KeyNamesFormat(log.RecordKeyNames{
+		Order: []log.RecordKey{log.KeyLevel, log.KeyMsg, log.KeyRequestID, log.KeySpanID, log.KeyParentID},
+	}, log.LogfmtFormat())))
+	return l, &buf
+}
+
+func TestHandler(t *testing.T) {
+	l, buf := testLogger()
+	var inner trace.Span
+	h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inner, _ = trace.SpanFromContext(r.Context())
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, "not here")
+	}), SetLogger(l))
+
+	req := httptest.NewRequest("GET", "/users?id=1", nil)
+	req.Header.Set(trace.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set("User-Agent", "test/1")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	if inner.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || inner.ParentID != "00f067aa0ba902b7" || inner.SpanID == "" {
+		t.Fatalf("unexpected span %+v", inner)
+	}
+	if id := w.Header().Get(trace.TraceIDHeaderKey); id != inner.TraceID {
+		t.Fatalf("echoed request id %q", id)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`level=warn msg="http request" request_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=` + inner.SpanID + ` parent_id=00f067aa0ba902b7`,
+		" method=GET path=/users status=404 bytes=8 duration=",
+		" remote_addr=192.0.2.1:1234 user_agent=test/1\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("%q not in %s", want, out)
+		}
+	}
+}
+
+func TestHandlerRequestID(t *testing.T) {
+	l, buf := testLogger()
+	h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		SetLogger(l), SetRequestIDHeader("X-Request-Id"), SetIDLength(4))
+
+	// an id from the request id header
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Request-Id", "abc")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if id := w.Header().Get("X-Request-Id"); id != "abc" {
+		t.Fatalf("echoed request id %q", id)
+	}
+	if !strings.HasPrefix(buf.String(), `level=info msg="http request" request_id=abc span_id=`) {
+		t.Fatalf("got %s", buf.String())
+	}
+
+	// a new id
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if id := w.Header().Get("X-Request-Id"); len(id) != 8 {
+		t.Fatalf("new request id %q", id)
+	}
+}
+
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	c1, c2 := net.Pipe()
+	c2.Close()
+	return c1, nil, nil
+}
+
+func TestWrapResponseWriter(t *testing.T) {
+	rw := WrapResponseWriter(httptest.NewRecorder())
+	if _, ok := rw.(http.Hijacker); ok {
+		t.Error("a recorder became a Hijacker")
+	}
+	rw.(http.Flusher).Flush()
+	if rw.Status() != http.StatusOK {
+		t.Errorf("status %d after Flush", rw.Status())
+	}
+
+	rw = WrapResponseWriter(hijackRecorder{httptest.NewRecorder()})
+	if _, ok := rw.(http.Flusher); !ok {
+		t.Error("lost Flusher")
+	}
+	conn, _, err := rw.(http.Hijacker).Hijack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if rw.Status() != http.StatusSwitchingProtocols {
+		t.Errorf("status %d after Hijack", rw.Status())
+	}
+
+	rw = WrapResponseWriter(httptest.NewRecorder())
+	io.Copy(rw, strings.NewReader("hello"))
+	if rw.Status() != http.StatusOK || rw.BytesWritten() != 5 {
+		t.Errorf("status %d, %d bytes", rw.Status(), rw.BytesWritten())
+	}
+
+	// informational responses aren't the status
+	rw = WrapResponseWriter(hijackRecorder{httptest.NewRecorder()})
+	rw.WriteHeader(http.StatusEarlyHints)
+	rw.WriteHeader(http.StatusCreated)
+	if rw.Status() != http.StatusCreated {
+		t.Errorf("status %d", rw.Status())
+	}
+	if _, ok := rw.Unwrap().(hijackRecorder); !ok {
+		t.Errorf("Unwrap returned %T", rw.Unwrap())
+	}
+}
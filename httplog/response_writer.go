@@ -0,0 +1,115 @@
+package httplog
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// ResponseWriter is an http.ResponseWriter which records the status and
+// the size of the response written through it.
+type ResponseWriter interface {
+	http.ResponseWriter
+	// Status returns the status code written, 0 if none was yet. A
+	// hijacked connection reports http.StatusSwitchingProtocols unless a
+	// status was written before.
+	Status() int
+	// BytesWritten returns the number of body bytes written.
+	BytesWritten() int64
+	// Unwrap returns the wrapped ResponseWriter, for
+	// http.ResponseController.
+	Unwrap() http.ResponseWriter
+}
+
+// WrapResponseWriter returns a ResponseWriter writing to w. It is an
+// http.Flusher or an http.Hijacker if w is.
+func WrapResponseWriter(w http.ResponseWriter) ResponseWriter {
+	if rw, ok := w.(ResponseWriter); ok {
+		return rw
+	}
+	rw := &responseWriter{ResponseWriter: w}
+	_, flusher := w.(http.Flusher)
+	_, hijacker := w.(http.Hijacker)
+	switch {
+	case flusher && hijacker:
+		return flushHijackWriter{rw}
+	case flusher:
+		return flushWriter{rw}
+	case hijacker:
+		return hijackWriter{rw}
+	}
+	return rw
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	status int   // 写入的状态码
+	bytes  int64 // 写入的 body 字节数
+}
+
+func (w *responseWriter) Status() int                 { return w.status }
+func (w *responseWriter) BytesWritten() int64         { return w.bytes }
+func (w *responseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func (w *responseWriter) WriteHeader(code int) {
+	// informational responses come before the final one
+	if w.status == 0 && (code >= 200 || code == http.StatusSwitchingProtocols) {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// ReadFrom keeps the sendfile path of the server's ResponseWriter for
+// io.Copy.
+func (w *responseWriter) ReadFrom(r io.Reader) (int64, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	var n int64
+	var err error
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		n, err = io.Copy(struct{ io.Writer }{w.ResponseWriter}, r)
+	}
+	w.bytes += n
+	return n, err
+}
+
+func (w *responseWriter) flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (w *responseWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buf, err := w.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, buf, err
+}
+
+type flushWriter struct{ *responseWriter }
+
+func (w flushWriter) Flush() { w.flush() }
+
+type hijackWriter struct{ *responseWriter }
+
+func (w hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
+
+type flushHijackWriter struct{ *responseWriter }
+
+func (w flushHijackWriter) Flush()                                       { w.flush() }
+func (w flushHijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
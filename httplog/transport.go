@@ -0,0 +1,145 @@
+package httplog
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	log "github.com/wuzuoliang/log"
+	"github.com/wuzuoliang/log/trace"
+)
+
+// redacted replaces the values of redacted headers and query parameters.
+const redacted = "REDACTED"
+
+var _defaultTransportOptions = func() options {
+	o := _defaultOptions
+	o.msg = "http client request"
+	return o
+}()
+
+// transport is the http.RoundTripper returned by Transport.
+type transport struct {
+	base http.RoundTripper
+	opts *options
+}
+
+// Transport returns an http.RoundTripper which makes requests with base,
+// http.DefaultTransport if nil, passes the trace of each request's
+// context on to the server, and logs each call once it returns:
+//
+//     msg="http client request" method=GET url="http://users/v1?token=REDACTED" status=200 duration=3ms request_id=...
+//
+// The call gets a span of its own, a child of the one in the context,
+// which is written into the request id header and the headers
+// SetPropagation picks. Calls whose context has no trace id, see
+// trace.FromContext, are logged without one. A call which fails is
+// logged at LvlError with its error.
+//
+//     client := &http.Client{Transport: httplog.Transport(nil)}
+//     resp, err := client.Do(req.WithContext(ctx))
+//
+func Transport(base http.RoundTripper, opts ...Options) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base, opts: newOptions(_defaultTransportOptions, opts)}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	o := t.opts
+	start := time.Now()
+
+	ctx := req.Context()
+	if _, ok := trace.FromContext(ctx); ok {
+		span, _ := trace.SpanFromContext(ctx)
+		span = span.Child()
+		ctx = trace.NewSpanContext(ctx, span)
+
+		// a RoundTripper mustn't change the caller's request
+		req = req.Clone(ctx)
+		req.Header.Set(o.header, span.TraceID)
+		trace.InjectHeader(req.Header, span, o.props...)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+
+	var level log.Level
+	status := 0
+	if err != nil {
+		level = log.LvlError
+	} else {
+		status = resp.StatusCode
+		level = o.level(status)
+	}
+	l := o.getLogger()
+	if !l.Enabled(level) {
+		return resp, err
+	}
+	fields := []log.Field{
+		log.String("method", req.Method),
+		log.String("url", o.redactURL(req.URL)),
+		log.Int("status", status),
+		log.Dur("duration", time.Since(start)),
+	}
+	if err != nil {
+		fields = append(fields, log.Err(err))
+	}
+	if o.logHeaders {
+		fields = append(fields, log.Any("headers", o.redactHeader(req.Header)))
+	}
+	l.LogFields(ctx, level, o.msg, fields...)
+	return resp, err
+}
+
+// CloseIdleConnections closes the idle connections of the underlying
+// RoundTripper, if it has a CloseIdleConnections method, so that
+// http.Client.CloseIdleConnections still works.
+func (t *transport) CloseIdleConnections() {
+	if c, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// redactURL returns u as a string with its password and redacted query
+// parameters replaced.
+func (o *options) redactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	c := *u
+	if _, ok := c.User.Password(); ok {
+		c.User = url.UserPassword(c.User.Username(), redacted)
+	}
+	if len(o.redactQuery) > 0 && c.RawQuery != "" {
+		q := c.Query()
+		changed := false
+		for k, vs := range q {
+			if o.redactQuery[k] {
+				for i := range vs {
+					vs[i] = redacted
+				}
+				changed = true
+			}
+		}
+		if changed {
+			c.RawQuery = q.Encode()
+		}
+	}
+	return c.String()
+}
+
+// redactHeader returns a copy of h with the values of redacted headers
+// replaced.
+func (o *options) redactHeader(h http.Header) http.Header {
+	c := h.Clone()
+	for k, vs := range c {
+		if o.redactHeaders[k] {
+			for i := range vs {
+				vs[i] = redacted
+			}
+		}
+	}
+	return c
+}
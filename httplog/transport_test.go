@@ -0,0 +1,79 @@
+package httplog
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/wuzuoliang/log/trace"
+)
+
+func TestTransport(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer srv.Close()
+
+	l, buf := testLogger()
+	client := &http.Client{Transport: Transport(nil, SetLogger(l), SetLogHeaders(true),
+		SetRedactQuery("token"), SetPropagation(trace.PropagateW3C, trace.PropagateB3))}
+
+	parent := trace.Span{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Flags: trace.FlagSampled}
+	ctx := trace.NewSpanContext(context.Background(), parent)
+	req, _ := http.NewRequest("GET", srv.URL+"/v1?token=secret&q=go", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	span, err := trace.ParseTraceparent(got.Get(trace.TraceparentHeader))
+	if err != nil || span.TraceID != parent.TraceID || span.SpanID == parent.SpanID {
+		t.Fatalf("traceparent %q: %+v %v", got.Get(trace.TraceparentHeader), span, err)
+	}
+	if b3, _ := trace.ParseB3(got.Get(trace.B3Header)); b3.SpanID != span.SpanID || b3.ParentID != parent.SpanID {
+		t.Errorf("b3 %q", got.Get(trace.B3Header))
+	}
+	if got.Get(trace.TraceIDHeaderKey) != parent.TraceID {
+		t.Errorf("request id %q", got.Get(trace.TraceIDHeaderKey))
+	}
+	if req.Header.Get(trace.TraceparentHeader) != "" {
+		t.Error("the caller's request was changed")
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		`level=warn msg="http client request" request_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=` + span.SpanID + ` parent_id=00f067aa0ba902b7`,
+		` method=GET url="` + srv.URL + `/v1?q=go&token=REDACTED" status=418 duration=`,
+		`Authorization:[REDACTED]`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("%q not in %s", want, out)
+		}
+	}
+	if strings.Contains(out, "secret") {
+		t.Errorf("secret logged: %s", out)
+	}
+}
+
+func TestTransportError(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	l, buf := testLogger()
+	client := &http.Client{Transport: Transport(nil, SetLogger(l))}
+	if _, err := client.Get("http://user:pw@" + strings.TrimPrefix(url, "http://")); err == nil {
+		t.Fatal("no error from a closed server")
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, `level=error msg="http client request" method=GET url=http://user:REDACTED@`) ||
+		!strings.Contains(out, " status=0 ") || !strings.Contains(out, " error=") {
+		t.Errorf("got %s", out)
+	}
+}
@@ -0,0 +1,364 @@
+package log
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/wuzuoliang/log/trace"
+)
+
+// builtinKeyPrefix is put in front of a key/value pair's key when it
+// clashes with one of the record's own keys, such as msg or level.
+const builtinKeyPrefix = "fields."
+
+// JsonFormat formats log records as JSON objects separated by newlines.
+// It is the equivalent of JsonFormatEx(false, true, opts...).
+func JsonFormat(opts ...FormatOptions) Format {
+	return JsonFormatEx(false, true, opts...)
+}
+
+// JsonFormatEx formats log records as JSON objects. If pretty is true,
+// records will be pretty-printed. If lineSeparated is true, records
+// will be logged with a new line between each record.
+//
+// Each object starts with the time, level, msg and location keys, and
+// the request id when there is one, or with the built-in fields chosen
+// by the record's RecordKeyNames, followed by the key/value pairs and
+// Fields in the order they were given. A key given more than once is
+// written once, with its last value, where it last appeared, so values
+// passed at the call site override those of the logger's context. A key
+// which clashes with one of the leading keys is written as "fields.key".
+//
+// Maps, slices and structs are encoded as JSON using encoding/json;
+// errors, fmt.Stringers and time values are written as strings. The
+// record's time is written as time.RFC3339Nano unless SetTimeFormat says
+// otherwise; epoch times are written as numbers.
+func JsonFormatEx(pretty, lineSeparated bool, opts ...FormatOptions) Format {
+	o := newFormatOptions(time.RFC3339Nano, opts)
+	return BufferFormatFunc(func(buf *bytes.Buffer, r *Record) {
+		if !pretty {
+			appendJSONRecord(buf, r, &o)
+		} else {
+			compact := getBuffer()
+			appendJSONRecord(compact, r, &o)
+			json.Indent(buf, compact.Bytes(), "", "    ")
+			putBuffer(compact)
+		}
+		if lineSeparated {
+			buf.WriteByte('\n')
+		}
+	})
+}
+
+func appendJSONRecord(buf *bytes.Buffer, r *Record, o *formatOptions) {
+	keys := r.KeyNames.order(jsonKeyOrder)
+	span := recordTrace(r)
+
+	buf.WriteByte('{')
+	n := 0
+	for _, key := range keys {
+		if traceKey(key) && traceID(span, key) == "" {
+			continue
+		}
+		if n > 0 {
+			buf.WriteByte(',')
+		}
+		n++
+		appendJSONString(buf, r.KeyNames.name(key))
+		buf.WriteByte(':')
+		switch key {
+		case KeyTime:
+			var scratch [64]byte
+			switch {
+			case o.epochTime():
+				buf.Write(o.appendTime(scratch[:0], r.Time))
+			case jsonSafeLayout(o.timeFormat):
+				buf.WriteByte('"')
+				buf.Write(o.appendTime(scratch[:0], r.Time))
+				buf.WriteByte('"')
+			default:
+				appendJSONString(buf, string(o.appendTime(scratch[:0], r.Time)))
+			}
+		case KeyLevel:
+			appendJSONString(buf, r.Level.String())
+		case KeyMsg:
+			appendJSONString(buf, r.Msg)
+		case KeyCall:
+			if r.CustomCaller == "" {
+				appendJSONString(buf, r.Call.String())
+			} else {
+				appendJSONString(buf, r.CustomCaller)
+			}
+		case KeyRequestID, KeySpanID, KeyParentID:
+			appendJSONString(buf, traceID(span, key))
+		}
+	}
+
+	pairs := len(r.KeyValues)/2 + len(r.Fields)
+	for i := 0; i < pairs; i++ {
+		k := jsonKeyAt(r, i)
+		if jsonKeyRepeated(r, k, i+1, pairs) {
+			continue
+		}
+		if n > 0 {
+			buf.WriteByte(',')
+		}
+		n++
+		if jsonBuiltinKey(r, keys, k, span) {
+			appendJSONString(buf, builtinKeyPrefix+k)
+		} else {
+			appendJSONString(buf, k)
+		}
+		buf.WriteByte(':')
+
+		if i < len(r.KeyValues)/2 {
+			if _, ok := r.KeyValues[2*i].(string); !ok {
+				appendJSONString(buf, fmt.Sprintf("%+v is not a string key", r.KeyValues[2*i]))
+				continue
+			}
+			appendJSONValue(buf, r.KeyValues[2*i+1])
+		} else {
+			r.Fields[i-len(r.KeyValues)/2].appendJSONValue(buf)
+		}
+	}
+	buf.WriteByte('}')
+}
+
+// jsonSafeLayout reports whether times formatted with layout can be
+// written in a JSON string as they are: only the layout's own literal
+// text could need escaping.
+func jsonSafeLayout(layout string) bool {
+	for i := 0; i < len(layout); i++ {
+		if c := layout[i]; c < 0x20 || c == '"' || c == '\\' || c >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonBuiltinKey reports whether k is the key of one of the built-in
+// fields written for r.
+func jsonBuiltinKey(r *Record, keys []RecordKey, k string, span trace.Span) bool {
+	for _, key := range keys {
+		if traceKey(key) && traceID(span, key) == "" {
+			continue
+		}
+		if r.KeyNames.name(key) == k {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonKeyAt returns the key of the i-th pair of r, counting the
+// key/value pairs first and then the Fields. Keys which aren't strings
+// are reported under errorKey.
+func jsonKeyAt(r *Record, i int) string {
+	if i < len(r.KeyValues)/2 {
+		if k, ok := r.KeyValues[2*i].(string); ok {
+			return k
+		}
+		return errorKey
+	}
+	return r.Fields[i-len(r.KeyValues)/2].Key
+}
+
+// jsonKeyRepeated reports whether k is the key of any pair from i to n.
+// Records rarely carry more than a handful of pairs, so a linear scan
+// beats building a set.
+func jsonKeyRepeated(r *Record, k string, i, n int) bool {
+	for ; i < n; i++ {
+		if jsonKeyAt(r, i) == k {
+			return true
+		}
+	}
+	return false
+}
+
+// appendJSONValue writes v as a JSON value. Scalars are written
+// directly; composite values go through encoding/json.
+func appendJSONValue(buf *bytes.Buffer, v interface{}) {
+	var scratch [64]byte
+	switch v := v.(type) {
+	case nil:
+		buf.WriteString("null")
+		return
+	case string:
+		appendJSONString(buf, v)
+		return
+	case bool:
+		buf.Write(strconv.AppendBool(scratch[:0], v))
+		return
+	case int:
+		buf.Write(strconv.AppendInt(scratch[:0], int64(v), 10))
+		return
+	case int8:
+		buf.Write(strconv.AppendInt(scratch[:0], int64(v), 10))
+		return
+	case int16:
+		buf.Write(strconv.AppendInt(scratch[:0], int64(v), 10))
+		return
+	case int32:
+		buf.Write(strconv.AppendInt(scratch[:0], int64(v), 10))
+		return
+	case int64:
+		buf.Write(strconv.AppendInt(scratch[:0], v, 10))
+		return
+	case uint:
+		buf.Write(strconv.AppendUint(scratch[:0], uint64(v), 10))
+		return
+	case uint8:
+		buf.Write(strconv.AppendUint(scratch[:0], uint64(v), 10))
+		return
+	case uint16:
+		buf.Write(strconv.AppendUint(scratch[:0], uint64(v), 10))
+		return
+	case uint32:
+		buf.Write(strconv.AppendUint(scratch[:0], uint64(v), 10))
+		return
+	case uint64:
+		buf.Write(strconv.AppendUint(scratch[:0], v, 10))
+		return
+	case float32:
+		appendJSONFloat(buf, float64(v), 32)
+		return
+	case float64:
+		appendJSONFloat(buf, v, 64)
+		return
+	case time.Time:
+		buf.WriteByte('"')
+		buf.Write(v.AppendFormat(scratch[:0], timeFormat))
+		buf.WriteByte('"')
+		return
+	}
+
+	// a nil pointer would make the methods below panic
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		if rv.IsNil() {
+			buf.WriteString("null")
+			return
+		}
+	}
+
+	switch m := v.(type) {
+	case json.Marshaler:
+		b, err := m.MarshalJSON()
+		if err == nil {
+			start := buf.Len()
+			if err = json.Compact(buf, b); err == nil {
+				return
+			}
+			buf.Truncate(start)
+		}
+		appendJSONString(buf, err.Error())
+		return
+	case error:
+		appendJSONString(buf, m.Error())
+		return
+	case fmt.Stringer:
+		appendJSONString(buf, m.String())
+		return
+	case encoding.TextMarshaler:
+		b, err := m.MarshalText()
+		if err != nil {
+			appendJSONString(buf, err.Error())
+			return
+		}
+		appendJSONString(buf, string(b))
+		return
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		start := buf.Len()
+		enc := json.NewEncoder(buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(v); err != nil {
+			buf.Truncate(start)
+			appendJSONString(buf, err.Error())
+			return
+		}
+		// Encode always terminates the value with a newline
+		buf.Truncate(buf.Len() - 1)
+	default:
+		appendJSONString(buf, fmt.Sprintf("%+v", v))
+	}
+}
+
+// appendJSONFloat writes f as a JSON number. JSON has no NaN or
+// infinities, so those are written as strings.
+func appendJSONFloat(buf *bytes.Buffer, f float64, bits int) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		appendJSONString(buf, strconv.FormatFloat(f, 'g', -1, bits))
+		return
+	}
+	var scratch [64]byte
+	buf.Write(strconv.AppendFloat(scratch[:0], f, 'g', -1, bits))
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString writes s as a quoted JSON string. Control
+// characters, quotes and backslashes are escaped, invalid UTF-8 is
+// replaced by U+FFFD, and U+2028/U+2029 are escaped so the output is
+// also valid JavaScript.
+func appendJSONString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			buf.WriteString(s[start:i])
+			switch c {
+			case '"', '\\':
+				buf.WriteByte('\\')
+				buf.WriteByte(c)
+			case '\n':
+				buf.WriteString(`\n`)
+			case '\r':
+				buf.WriteString(`\r`)
+			case '\t':
+				buf.WriteString(`\t`)
+			default:
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hexDigits[c>>4])
+				buf.WriteByte(hexDigits[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf.WriteString(s[start:i])
+			buf.WriteString(`\ufffd`)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			buf.WriteString(s[start:i])
+			buf.WriteString(`\u202`)
+			buf.WriteByte(hexDigits[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buf.WriteString(s[start:])
+	buf.WriteByte('"')
+}
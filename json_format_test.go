@@ -0,0 +1,104 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func jsonRecord(kvs ...interface{}) *Record {
+	kvs, fields := splitFields(kvs)
+	return &Record{
+		Time:         time.Date(2026, 10, 17, 13, 4, 5, 0, time.UTC),
+		Level:        LvlInfo,
+		Msg:          "hello",
+		KeyValues:    kvs,
+		Fields:       fields,
+		CustomCaller: "main.go:12",
+		KeyNames:     defaultRecordKeyNames,
+	}
+}
+
+func TestJsonOrder(t *testing.T) {
+	t.Parallel()
+
+	got := string(JsonFormat().Format(jsonRecord("z", 1, "a", "x", Int("m", 2), "b", true)))
+	want := `{"time":"2026-10-17T13:04:05Z","level":"info","msg":"hello","location":"main.go:12","z":1,"a":"x","b":true,"m":2}` + "\n"
+	if got != want {
+		t.Fatalf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestJsonDuplicates(t *testing.T) {
+	t.Parallel()
+
+	r := jsonRecord("user", "ctx", "n", 1, "msg", "shadow", "user", "call", 5, "bad key", String("n", "field"))
+	got := string(JsonFormatEx(false, false).Format(r))
+	want := `{"time":"2026-10-17T13:04:05Z","level":"info","msg":"hello","location":"main.go:12",` +
+		`"fields.msg":"shadow","user":"call","error":"5 is not a string key","n":"field"}`
+	if got != want {
+		t.Fatalf("got  %s\nwant %s", got, want)
+	}
+}
+
+type jsonStringer struct{ s string }
+
+func (j *jsonStringer) String() string { return j.s }
+
+func TestJsonValues(t *testing.T) {
+	t.Parallel()
+
+	type inner struct {
+		A int    `json:"a"`
+		B string `json:"b,omitempty"`
+	}
+	var nilStringer *jsonStringer
+	r := jsonRecord(
+		"esc", "quote\" back\\ nl\n ctl\x01 bad\xff ls\u2028 <&>",
+		"map", map[string]interface{}{"k": []int{1, 2}},
+		"struct", inner{A: 1},
+		"ptr", &inner{B: "x"},
+		"nilptr", nilStringer,
+		"stringer", &jsonStringer{"str"},
+		"err", errors.New("boom"),
+		"nan", math.NaN(),
+		"float", 1.5,
+		"dur", time.Second,
+		"fn", func() {},
+	)
+	b := JsonFormat().Format(r)
+
+	if !bytes.Contains(b, []byte(`"esc":"quote\" back\\ nl\n ctl\u0001 bad\ufffd ls\u2028 <&>"`)) {
+		t.Fatalf("bad escaping in %s", b)
+	}
+	for _, want := range []string{
+		`"map":{"k":[1,2]}`, `"struct":{"a":1}`, `"ptr":{"a":0,"b":"x"}`, `"nilptr":null`,
+		`"stringer":"str"`, `"err":"boom"`, `"nan":"NaN"`, `"float":1.5`, `"dur":"1s"`,
+	} {
+		if !bytes.Contains(b, []byte(want)) {
+			t.Fatalf("missing %s in %s", want, b)
+		}
+	}
+
+	var v map[string]interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		t.Fatalf("invalid JSON %s: %v", b, err)
+	}
+	if v["esc"] != "quote\" back\\ nl\n ctl\x01 bad\ufffd ls\u2028 <&>" {
+		t.Fatalf("escaped string didn't round trip: %q", v["esc"])
+	}
+}
+
+func TestJsonPretty(t *testing.T) {
+	t.Parallel()
+
+	b := JsonFormatEx(true, true).Format(jsonRecord("a", []int{1}))
+	want := "{\n    \"time\": \"2026-10-17T13:04:05Z\",\n    \"level\": \"info\",\n    \"msg\": \"hello\",\n" +
+		"    \"location\": \"main.go:12\",\n    \"a\": [\n        1\n    ]\n}\n"
+	if string(b) != want {
+		t.Fatalf("got %s\nwant %s", b, want)
+	}
+}
@@ -0,0 +1,161 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// A RecordKey identifies one of the built-in fields every record is
+// written with.
+type RecordKey int
+
+const (
+	KeyTime RecordKey = iota
+	KeyLevel
+	KeyMsg
+	KeyCall
+	KeyRequestID // the trace id, see TraceExtractor
+	KeySpanID
+	KeyParentID
+)
+
+var recordKeyNames = [...]string{
+	KeyTime:      timeKey,
+	KeyLevel:     levelKey,
+	KeyMsg:       msgKey,
+	KeyCall:      "call",
+	KeyRequestID: requestID,
+	KeySpanID:    spanIDKey,
+	KeyParentID:  parentIDKey,
+}
+
+// String returns the name of k: time, level, msg, call, request_id,
+// span_id or parent_id.
+func (k RecordKey) String() string {
+	if k >= 0 && int(k) < len(recordKeyNames) {
+		return recordKeyNames[k]
+	}
+	return fmt.Sprintf("key(%d)", int(k))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (k RecordKey) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. It accepts the
+// names String returns, and location or caller for KeyCall.
+func (k *RecordKey) UnmarshalText(text []byte) error {
+	s := strings.ToLower(string(text))
+	switch s {
+	case locationKey, "caller":
+		*k = KeyCall
+		return nil
+	}
+	for i, name := range recordKeyNames {
+		if s == name {
+			*k = RecordKey(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("log: unknown record key %q", text)
+}
+
+// Default orders of the built-in fields, used when RecordKeyNames.Order
+// is nil.
+var (
+	jsonKeyOrder          = []RecordKey{KeyTime, KeyLevel, KeyMsg, KeyCall, KeyRequestID, KeySpanID, KeyParentID}
+	logfmtKeyOrder        = []RecordKey{KeyTime, KeyLevel, KeyCall, KeyMsg, KeyRequestID, KeySpanID, KeyParentID}
+	terminalKeyOrder      = []RecordKey{KeyLevel, KeyCall, KeyTime, KeyMsg, KeyRequestID, KeySpanID, KeyParentID}
+	terminalColorKeyOrder = []RecordKey{KeyLevel, KeyTime, KeyCall, KeyMsg, KeyRequestID, KeySpanID, KeyParentID}
+)
+
+// inherit returns k with every empty name, and the order if k has none,
+// taken from base.
+func (k RecordKeyNames) inherit(base RecordKeyNames) RecordKeyNames {
+	if k.Order == nil {
+		k.Order = base.Order
+	}
+	if k.Time == "" {
+		k.Time = base.Time
+	}
+	if k.Msg == "" {
+		k.Msg = base.Msg
+	}
+	if k.Level == "" {
+		k.Level = base.Level
+	}
+	if k.Call == "" {
+		k.Call = base.Call
+	}
+	if k.RequestID == "" {
+		k.RequestID = base.RequestID
+	}
+	if k.SpanID == "" {
+		k.SpanID = base.SpanID
+	}
+	if k.ParentID == "" {
+		k.ParentID = base.ParentID
+	}
+	return k
+}
+
+// clone returns a copy of k which doesn't share its Order.
+func (k RecordKeyNames) clone() RecordKeyNames {
+	if k.Order != nil {
+		k.Order = append(make([]RecordKey, 0, len(k.Order)), k.Order...)
+	}
+	return k
+}
+
+// order returns the built-in fields to write, in order, or def if k
+// doesn't say.
+func (k *RecordKeyNames) order(def []RecordKey) []RecordKey {
+	if k.Order != nil {
+		return k.Order
+	}
+	return def
+}
+
+// name returns the key key is written under.
+func (k *RecordKeyNames) name(key RecordKey) string {
+	switch key {
+	case KeyTime:
+		return k.Time
+	case KeyLevel:
+		return k.Level
+	case KeyMsg:
+		return k.Msg
+	case KeyCall:
+		return k.Call
+	case KeyRequestID:
+		return k.RequestID
+	case KeySpanID:
+		return k.SpanID
+	case KeyParentID:
+		return k.ParentID
+	}
+	return ""
+}
+
+// KeyNamesFormat formats records with fmtr as if they had been logged
+// with the given key names, whichever logger they came from. Empty names,
+// and a nil Order, keep the record's own. For example, for a pipeline
+// which expects severity and message:
+//
+//     log.KeyNamesFormat(log.RecordKeyNames{
+//         Level: "severity",
+//         Msg:   "message",
+//         Order: []log.RecordKey{log.KeyTime, log.KeyLevel, log.KeyMsg},
+//     }, log.JsonFormat())
+//
+func KeyNamesFormat(names RecordKeyNames, fmtr Format) Format {
+	names = names.clone()
+	return BufferFormatFunc(func(buf *bytes.Buffer, r *Record) {
+		c := r.Clone()
+		c.KeyNames = names.inherit(r.KeyNames)
+		formatBuffer(buf, fmtr, c)
+		releaseRecord(c)
+	})
+}
@@ -0,0 +1,74 @@
+package log
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestKeyNamesLogger(t *testing.T) {
+	t.Parallel()
+
+	l, buf := testFormatter(JsonFormat())
+	l.SetKeyNames(RecordKeyNames{Time: "ts", Level: "severity", Msg: "message"})
+	child := l.New("a", 1)
+	child.Info("hello", "severity", "high")
+
+	got := buf.String()
+	for _, want := range []string{`{"ts":"`, `"severity":"info","message":"hello","location":"`, `"a":1,"fields.severity":"high"}`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %s in %s", want, got)
+		}
+	}
+	if names := child.GetKeyNames(); names.Msg != "message" || names.RequestID != requestID {
+		t.Errorf("child key names: %+v", names)
+	}
+}
+
+func TestKeyNamesOrder(t *testing.T) {
+	t.Parallel()
+
+	names := RecordKeyNames{
+		Level: "severity",
+		Msg:   "message",
+		Order: []RecordKey{KeyMsg, KeyLevel, KeyRequestID},
+	}
+	ctx := context.WithValue(context.Background(), requestID, "r1")
+	for _, tt := range []struct {
+		fmtr Format
+		want string
+	}{
+		{JsonFormat(), `{"message":"hi","severity":"warn","request_id":"r1","msg":"x"}` + "\n"},
+		{LogfmtFormat(), `message=hi severity=warn request_id=r1 msg=x` + "\n"},
+		{TerminalFormat(), "message=hi \x1b[33mWARN\x1b[0m request_id=r1 \x1b[33mmsg\x1b[0m=x\n"},
+	} {
+		l, buf := testFormatter(KeyNamesFormat(names, tt.fmtr))
+		l.WarnContext(ctx, "hi", "msg", "x")
+		if got := buf.String(); got != tt.want {
+			t.Errorf("got  %q\nwant %q", got, tt.want)
+		}
+	}
+
+	// no built-in fields at all
+	l, buf := testFormatter(KeyNamesFormat(RecordKeyNames{Order: []RecordKey{}}, LogfmtFormat()))
+	l.Info("hi", "a", 1)
+	if got := buf.String(); got != "a=1\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestRecordKeyText(t *testing.T) {
+	for _, s := range []string{"time", "level", "msg", "call", "location", "request_id"} {
+		var k RecordKey
+		if err := k.UnmarshalText([]byte(s)); err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+		if s != "location" && k.String() != s {
+			t.Errorf("%s: round trips to %s", s, k)
+		}
+	}
+	var k RecordKey
+	if err := k.UnmarshalText([]byte("stack")); err == nil {
+		t.Error("unknown key accepted")
+	}
+}
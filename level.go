@@ -0,0 +1,246 @@
+package log
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Level 日志级别
+type Level int
+
+const (
+	LvlFatal = iota
+	LvlError
+	LvlWarn
+	LvlInfo
+	LvlDebug
+	LvlTrace
+
+	LvlFatalStr = "fatal"
+	LvlErrorStr = "error"
+	LvlWarnStr  = "warn"
+	LvlInfoStr  = "info"
+	LvlDebugStr = "debug"
+	LvlTraceStr = "trace"
+)
+
+// levelInfo describes how a level is written
+type levelInfo struct {
+	name  string // String 返回的名字, 小写
+	short string // TerminalFormat 中显示的代码
+	color int    // TerminalFormat 中的颜色, 0 为不着色
+}
+
+// levelTable is replaced as a whole whenever a level is registered, so
+// readers never need a lock.
+type levelTable struct {
+	levels map[Level]levelInfo
+	names  map[string]Level // 名字、代码和别名, 小写
+	order  []Level          // 由重到轻的全部级别
+	ranks  map[Level]int    // 级别在 order 中的位置, 无自定义级别时为 nil
+	after  map[Level]Level  // 自定义级别紧随其后的级别
+}
+
+var (
+	levelMu sync.Mutex // serializes RegisterLevel
+
+	_levelTablePtr unsafe.Pointer = unsafe.Pointer(&levelTable{ // *levelTable
+		levels: map[Level]levelInfo{
+			LvlFatal: {LvlFatalStr, "FATAL", 35},
+			LvlError: {LvlErrorStr, "ERROR", 31},
+			LvlWarn:  {LvlWarnStr, "WARN", 33},
+			LvlInfo:  {LvlInfoStr, "INFO", 32},
+			LvlDebug: {LvlDebugStr, "DEBUG", 36},
+			LvlTrace: {LvlTraceStr, "TRACE", 30},
+		},
+		names: map[string]Level{
+			"fatal": LvlFatal, "fatl": LvlFatal, "crit": LvlFatal, "critical": LvlFatal,
+			"error": LvlError, "eror": LvlError, "err": LvlError,
+			"warn": LvlWarn, "warning": LvlWarn,
+			"info":  LvlInfo,
+			"debug": LvlDebug, "dbug": LvlDebug,
+			"trace": LvlTrace, "trce": LvlTrace,
+		},
+		order: []Level{LvlFatal, LvlError, LvlWarn, LvlInfo, LvlDebug, LvlTrace},
+	})
+)
+
+func getLevelTable() *levelTable {
+	return (*levelTable)(atomic.LoadPointer(&_levelTablePtr))
+}
+
+// rank returns the position of l among the levels, the most severe first.
+// Unregistered levels keep their number's place: before every level if
+// negative, after every registered one otherwise.
+func (t *levelTable) rank(l Level) int {
+	if t.ranks == nil {
+		// only the built-in levels, in their numeric order
+		return int(l)
+	}
+	if r, ok := t.ranks[l]; ok {
+		return r
+	}
+	if l < 0 {
+		return int(l)
+	}
+	return len(t.order) + int(l)
+}
+
+// RegisterLevel adds a custom level, ordered directly below after: a
+// handler filtering at after's level or any less severe one lets it
+// through. level is any number above LvlTrace that fits in 16 bits; its
+// value doesn't order it, so compare levels with Compare rather than <
+// and >. name is what String, LogfmtFormat and JsonFormatEx write and
+// what ParseLevel accepts; short is the code TerminalFormat prints and
+// defaults to the upper case name; color is an ANSI color code for
+// TerminalFormat, or 0 for none.
+//
+//     const LvlNotice log.Level = 10
+//
+//     func init() {
+//         log.RegisterLevel(LvlNotice, log.LvlWarn, "notice", "NOTE", 34)
+//     }
+//
+// Registering a level again with the same place, name, short code and
+// color is a no-op; anything else already taken is an error.
+func RegisterLevel(level, after Level, name, short string, color int) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if short == "" {
+		short = strings.ToUpper(name)
+	}
+	if level <= LvlTrace || level > math.MaxInt16 {
+		return fmt.Errorf("log: custom level %d is not between %d and %d", int(level), LvlTrace+1, math.MaxInt16)
+	}
+	if name == "" {
+		return fmt.Errorf("log: level %d has no name", int(level))
+	}
+
+	levelMu.Lock()
+	defer levelMu.Unlock()
+
+	old := getLevelTable()
+	info := levelInfo{name: name, short: short, color: color}
+	if cur, ok := old.levels[level]; ok {
+		if cur == info && old.after[level] == after {
+			return nil
+		}
+		return fmt.Errorf("log: level %d is already registered as %q", int(level), cur.name)
+	}
+	if _, ok := old.levels[after]; !ok {
+		return fmt.Errorf("log: level %d to order %q after is not registered", int(after), name)
+	}
+	if _, ok := old.names[name]; ok {
+		return fmt.Errorf("log: level name %q is already taken", name)
+	}
+
+	t := &levelTable{
+		levels: make(map[Level]levelInfo, len(old.levels)+1),
+		names:  make(map[string]Level, len(old.names)+2),
+		order:  make([]Level, 0, len(old.order)+1),
+		ranks:  make(map[Level]int, len(old.order)+1),
+		after:  make(map[Level]Level, len(old.after)+1),
+	}
+	for l, i := range old.levels {
+		t.levels[l] = i
+	}
+	for n, l := range old.names {
+		t.names[n] = l
+	}
+	for l, a := range old.after {
+		t.after[l] = a
+	}
+	t.levels[level] = info
+	t.after[level] = after
+	t.names[name] = level
+	if _, taken := t.names[strings.ToLower(short)]; !taken {
+		t.names[strings.ToLower(short)] = level
+	}
+	for _, l := range old.order {
+		t.order = append(t.order, l)
+		if l == after {
+			t.order = append(t.order, level)
+		}
+	}
+	for r, l := range t.order {
+		t.ranks[l] = r
+	}
+	atomic.StorePointer(&_levelTablePtr, unsafe.Pointer(t))
+	return nil
+}
+
+// Compare returns -1, 0 or +1 as l is more severe than, as severe as or
+// less severe than o, with custom levels where RegisterLevel put them.
+func (l Level) Compare(o Level) int {
+	t := getLevelTable()
+	switch a, b := t.rank(l), t.rank(o); {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Returns the name of a Level
+func (l Level) String() string {
+	if info, ok := getLevelTable().levels[l]; ok {
+		return info.name
+	}
+	return fmt.Sprintf("level(%d)", int(l))
+}
+
+// short returns the code TerminalFormat prints for l.
+func (l Level) short() string {
+	if info, ok := getLevelTable().levels[l]; ok {
+		return info.short
+	}
+	return strings.ToUpper(l.String())
+}
+
+// color returns the terminal color of l.
+func (l Level) color() int {
+	return getLevelTable().levels[l].color
+}
+
+// registered reports whether l is a built-in or registered level.
+func (l Level) registered() bool {
+	_, ok := getLevelTable().levels[l]
+	return ok
+}
+
+// ParseLevel returns the level with the given name, the inverse of
+// Level.String. It ignores case and also accepts the short codes and
+// common aliases such as "warning", "crit" and "dbug".
+func ParseLevel(s string) (Level, error) {
+	if l, ok := getLevelTable().names[strings.ToLower(strings.TrimSpace(s))]; ok {
+		return l, nil
+	}
+	return 0, fmt.Errorf("log: unknown level %q", s)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler using ParseLevel.
+func (l *Level) UnmarshalText(text []byte) error {
+	lvl, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = lvl
+	return nil
+}
+
+// Set implements flag.Value, so a Level can be a command line flag:
+//
+//     level := log.Level(log.LvlInfo)
+//     flag.Var(&level, "level", "log level")
+func (l *Level) Set(s string) error {
+	return l.UnmarshalText([]byte(s))
+}
@@ -0,0 +1,103 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	t.Parallel()
+
+	for s, want := range map[string]Level{
+		"fatal": LvlFatal, "CRIT": LvlFatal, "Error": LvlError, "eror": LvlError,
+		"warning": LvlWarn, "WARN": LvlWarn, " info ": LvlInfo, "dbug": LvlDebug, "trace": LvlTrace,
+	} {
+		got, err := ParseLevel(s)
+		if err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v", s, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Errorf("expected an error for an unknown level")
+	}
+	if s := Level(42).String(); s != "level(42)" {
+		t.Errorf("unknown level printed as %q", s)
+	}
+}
+
+func TestLevelText(t *testing.T) {
+	t.Parallel()
+
+	var cfg struct {
+		Level Level `json:"level"`
+	}
+	if err := json.Unmarshal([]byte(`{"level":"warning"}`), &cfg); err != nil || cfg.Level != LvlWarn {
+		t.Fatalf("unmarshal: %v %v", cfg.Level, err)
+	}
+	b, err := json.Marshal(cfg)
+	if err != nil || string(b) != `{"level":"warn"}` {
+		t.Fatalf("marshal: %s %v", b, err)
+	}
+
+	level := Level(LvlInfo)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&level, "level", "log level")
+	if err := fs.Parse([]string{"-level", "DEBUG"}); err != nil || level != LvlDebug {
+		t.Fatalf("flag: %v %v", level, err)
+	}
+}
+
+func TestRegisterLevel(t *testing.T) {
+	t.Parallel()
+
+	const lvlNotice Level = 10
+	if err := RegisterLevel(lvlNotice, LvlWarn, "Notice", "NOTE", 34); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterLevel(lvlNotice, LvlWarn, "notice", "NOTE", 34); err != nil {
+		t.Fatalf("registering the same level twice: %v", err)
+	}
+	for _, bad := range []struct {
+		level, after Level
+		name         string
+	}{
+		{lvlNotice, LvlWarn, "other"}, {lvlNotice, LvlInfo, "notice"}, {11, LvlWarn, "warning"},
+		{LvlFatal, LvlWarn, "panic"}, {LvlTrace, LvlWarn, "verbose"}, {11, LvlWarn, ""}, {11, 42, "unknown"},
+	} {
+		if err := RegisterLevel(bad.level, bad.after, bad.name, "", 0); err == nil {
+			t.Errorf("expected an error registering %d as %q", bad.level, bad.name)
+		}
+	}
+	if LvlTrace != 5 || lvlNotice.Compare(LvlWarn) <= 0 || lvlNotice.Compare(LvlInfo) >= 0 ||
+		Level(LvlInfo).Compare(LvlWarn) <= 0 || Level(42).Compare(LvlTrace) <= 0 {
+		t.Fatalf("levels out of order")
+	}
+	if l, err := ParseLevel("note"); err != nil || l != lvlNotice {
+		t.Fatalf("short code not parsed: %v %v", l, err)
+	}
+
+	var buf bytes.Buffer
+	l := New()
+	l.SetHandler(LvlFilterHandler(LvlInfo, MultiHandler(
+		StreamHandler(&buf, TerminalFormat()),
+		StreamHandler(&buf, LogfmtFormat()),
+		StreamHandler(&buf, JsonFormat()),
+	)))
+	l.LogLevel(lvlNotice, "quota low")
+	out := buf.String()
+	for _, want := range []string{"\x1b[34mNOTE\x1b[0m", "level=notice", `"level":"notice"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in %q", want, out)
+		}
+	}
+
+	buf.Reset()
+	l.SetOutLevel(LvlWarn)
+	l.LogLevel(lvlNotice, "hidden")
+	if buf.Len() != 0 {
+		t.Fatalf("notice written at warn: %q", buf.String())
+	}
+}
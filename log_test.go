@@ -5,8 +5,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"regexp"
 	"runtime"
+	"strings"
 	"sync"
 	"testing"
 )
@@ -14,7 +17,7 @@ import (
 func testHandler() (Handler, *Record) {
 	rec := new(Record)
 	return FuncHandler(func(r *Record) error {
-		*rec = *r
+		*rec = *r.Clone()
 		return nil
 	}), rec
 }
@@ -119,7 +122,7 @@ func TestJson(t *testing.T) {
 	validate("msg", "some message")
 	validate("x", float64(1)) // all numbers are floats in JSON land
 	validate("y", 3.2)
-	validate("lvl", "eror")
+	validate("level", "error")
 }
 
 type testtype struct {
@@ -139,10 +142,14 @@ func TestLogfmt(t *testing.T) {
 	l.Error("some message", "x", 1, "y", 3.2, "equals", "=", "quote", "\"",
 		"nil", nilVal, "carriage_return", "bang"+string('\r')+"foo", "tab", "bar	baz", "newline", "foo\nbar")
 
-	// skip timestamp in comparison
-	got := buf.Bytes()[27:buf.Len()]
-	expected := []byte(`lvl=eror msg="some message" x=1 y=3.200 equals="=" quote="\"" nil=nil carriage_return="bang\rfoo" tab="bar\tbaz" newline="foo\nbar"` + "\n")
-	if !bytes.Equal(got, expected) {
+	// skip timestamp and location in comparison
+	got := buf.String()
+	if !strings.HasPrefix(got, `time="`) || !strings.Contains(got, `" level=error location=log_test.go:`) {
+		t.Fatalf("Got %s, expected time, level and location first", got)
+	}
+	got = got[strings.Index(got, " msg="):]
+	expected := ` msg="some message" x=1 y=3.200 equals="=" quote="\"" nil=nil carriage_return="bang\rfoo" tab="bar\tbaz" newline="foo\nbar"` + "\n"
+	if got != expected {
 		t.Fatalf("Got %s, expected %s", got, expected)
 	}
 }
@@ -534,3 +541,156 @@ func TestCanLog(t *testing.T) {
 
 	t.Log()
 }
+
+type closeRecorder struct {
+	logged  int
+	flushed int
+	closed  int
+}
+
+func (h *closeRecorder) Log(r *Record) error {
+	h.logged++
+	return nil
+}
+
+func (h *closeRecorder) Flush() error {
+	h.flushed++
+	return nil
+}
+
+func (h *closeRecorder) Close() error {
+	h.closed++
+	return nil
+}
+
+func TestCloseHandlerPropagates(t *testing.T) {
+	t.Parallel()
+
+	h1, h2 := &closeRecorder{}, &closeRecorder{}
+	h := LazyHandler(SyncHandler(MultiHandler(
+		LvlFilterHandler(LvlError, h1),
+		FailoverHandler(h2, DiscardHandler()),
+	)))
+
+	if err := FlushHandler(h); err != nil {
+		t.Fatalf("unexpected flush error: %v", err)
+	}
+	if err := CloseHandler(h); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+
+	for i, r := range []*closeRecorder{h1, h2} {
+		if r.flushed != 1 || r.closed != 1 {
+			t.Fatalf("handler %d: got flushed=%d closed=%d, expected 1 and 1", i, r.flushed, r.closed)
+		}
+	}
+}
+
+func TestBufferedHandlerClose(t *testing.T) {
+	t.Parallel()
+
+	rec := &closeRecorder{}
+	h := BufferedHandler(100, rec)
+	l := New()
+	l.SetHandler(h)
+
+	for i := 0; i < 50; i++ {
+		l.Info("buffered", "i", i)
+	}
+	if err := FlushHandler(h); err != nil {
+		t.Fatalf("unexpected flush error: %v", err)
+	}
+	if rec.logged != 50 || rec.flushed != 1 {
+		t.Fatalf("after flush got logged=%d flushed=%d, expected 50 and 1", rec.logged, rec.flushed)
+	}
+
+	for i := 0; i < 50; i++ {
+		l.Info("buffered", "i", i)
+	}
+	if err := CloseHandler(h); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+	if rec.logged != 100 || rec.closed != 1 {
+		t.Fatalf("after close got logged=%d closed=%d, expected 100 and 1", rec.logged, rec.closed)
+	}
+
+	if err := h.Log(&Record{Msg: "late"}); err != ErrHandlerClosed {
+		t.Fatalf("got %v logging after close, expected %v", err, ErrHandlerClosed)
+	}
+	if err := CloseHandler(h); err != nil {
+		t.Fatalf("unexpected error closing twice: %v", err)
+	}
+}
+
+func TestFileHandlerClose(t *testing.T) {
+	t.Parallel()
+
+	f, err := ioutil.TempFile("", "log-close")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	h, err := FileHandler(f.Name(), LogfmtFormat())
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := New()
+	l.SetHandler(h)
+	l.Info("before close")
+
+	if err := CloseHandler(l.GetHandler()); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+	if err := CloseHandler(h); err != nil {
+		t.Fatalf("unexpected error closing twice: %v", err)
+	}
+	if err := h.Log(&Record{Msg: "after close"}); err == nil {
+		t.Fatalf("expected an error writing to a closed file")
+	}
+}
+
+func TestChannelHandlerOwnsRecords(t *testing.T) {
+	t.Parallel()
+
+	recs := make(chan *Record, 10)
+	l := New()
+	l.SetHandler(ChannelHandler(recs))
+	for i := 0; i < 10; i++ {
+		l.Info("rec", "i", i)
+	}
+	close(recs)
+
+	i := 0
+	for r := range recs {
+		if r.Msg != "rec" || r.KeyValues[1] != i {
+			t.Fatalf("record %d was reused: %+v", i, r)
+		}
+		i++
+	}
+}
+
+func TestBufferFormatCopies(t *testing.T) {
+	t.Parallel()
+
+	fmtr := LogfmtFormat()
+	if _, ok := fmtr.(BufferFormat); !ok {
+		t.Fatalf("LogfmtFormat is not a BufferFormat")
+	}
+	r := &Record{Msg: "one", KeyNames: defaultRecordKeyNames}
+	first := fmtr.Format(r)
+	want := string(first)
+	r.Msg = "two"
+	fmtr.Format(r)
+	if string(first) != want {
+		t.Fatalf("formatted bytes were overwritten: %q, want %q", first, want)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("prefix ")
+	fmtr.(BufferFormat).FormatBuffer(&buf, r)
+	if got := buf.String(); got != "prefix "+string(fmtr.Format(r)) {
+		t.Fatalf("FormatBuffer didn't append: %q", got)
+	}
+}
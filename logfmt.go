@@ -0,0 +1,305 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/wuzuoliang/log/trace"
+)
+
+// logfmtSafe reports whether r may appear in a key or an unquoted value.
+func logfmtSafe(r rune) bool {
+	return r > ' ' && r != '=' && r != '"' && r != 0x7f && r != utf8.RuneError && !unicode.IsSpace(r)
+}
+
+// appendLogfmtKey writes k as a logfmt key. Characters a key can't
+// hold, such as spaces, '=' and '"', are replaced by '_', and an empty
+// key is written as "_".
+func appendLogfmtKey(buf *bytes.Buffer, k string) {
+	if k == "" {
+		buf.WriteByte('_')
+		return
+	}
+	start := 0
+	for i := 0; i < len(k); {
+		r, size := utf8.DecodeRuneInString(k[i:])
+		if logfmtSafe(r) || (r == utf8.RuneError && size > 1) {
+			i += size
+			continue
+		}
+		buf.WriteString(k[start:i])
+		buf.WriteByte('_')
+		i += size
+		start = i
+	}
+	buf.WriteString(k[start:])
+}
+
+// appendLogfmtString writes s as a logfmt value: as it is if it can be,
+// quoted otherwise. Inside quotes, quotes and backslashes are escaped,
+// control characters are written as \n, \r, \t or \u00XX, and invalid
+// UTF-8 is replaced by \ufffd.
+func appendLogfmtString(buf *bytes.Buffer, s string) {
+	if !logfmtNeedsQuotes(s) {
+		buf.WriteString(s)
+		return
+	}
+	buf.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if r == utf8.RuneError && size == 1 {
+				buf.WriteString(s[start:i])
+				buf.WriteString(`\ufffd`)
+				start = i + 1
+			}
+			i += size
+			continue
+		}
+		if c >= 0x20 && c != 0x7f && c != '"' && c != '\\' {
+			i++
+			continue
+		}
+		buf.WriteString(s[start:i])
+		switch c {
+		case '"', '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			buf.WriteString(`\u00`)
+			buf.WriteByte(hexDigits[c>>4])
+			buf.WriteByte(hexDigits[c&0xf])
+		}
+		i++
+		start = i
+	}
+	buf.WriteString(s[start:])
+	buf.WriteByte('"')
+}
+
+func logfmtNeedsQuotes(s string) bool {
+	if s == "" {
+		return true
+	}
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c <= ' ' || c == '=' || c == '"' || c == 0x7f {
+				return true
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if (r == utf8.RuneError && size == 1) || unicode.IsSpace(r) {
+			return true
+		}
+		i += size
+	}
+	return false
+}
+
+var stringBufPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// escapeString returns s as a logfmt value, see appendLogfmtString.
+func escapeString(s string) string {
+	if !logfmtNeedsQuotes(s) {
+		return s
+	}
+	e := stringBufPool.Get().(*bytes.Buffer)
+	appendLogfmtString(e, s)
+	ret := e.String()
+	e.Reset()
+	stringBufPool.Put(e)
+	return ret
+}
+
+// ParseLogfmtPairs splits a line of logfmt into its keys and values,
+// returned as key, value, key, value... Quoted values are unquoted; a key
+// without a value gets the empty string.
+func ParseLogfmtPairs(line []byte) ([]string, error) {
+	var pairs []string
+	i := 0
+	for {
+		for i < len(line) && line[i] <= ' ' {
+			i++
+		}
+		if i == len(line) {
+			return pairs, nil
+		}
+
+		start := i
+		for i < len(line) && line[i] > ' ' && line[i] != '=' && line[i] != '"' {
+			i++
+		}
+		if i == start {
+			return nil, fmt.Errorf("log: logfmt: unexpected %q at offset %d", line[i], i)
+		}
+		key := string(line[start:i])
+		if i == len(line) || line[i] != '=' {
+			if i < len(line) && line[i] == '"' {
+				return nil, fmt.Errorf("log: logfmt: unexpected '\"' at offset %d", i)
+			}
+			pairs = append(pairs, key, "")
+			continue
+		}
+		i++ // '='
+
+		var value string
+		if i < len(line) && line[i] == '"' {
+			start = i
+			for i++; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(line) {
+				return nil, fmt.Errorf("log: logfmt: unterminated value at offset %d", start)
+			}
+			i++
+			var err error
+			if value, err = strconv.Unquote(string(line[start:i])); err != nil {
+				return nil, fmt.Errorf("log: logfmt: bad quoted value at offset %d", start)
+			}
+			if i < len(line) && line[i] > ' ' {
+				return nil, fmt.Errorf("log: logfmt: unexpected %q at offset %d", line[i], i)
+			}
+		} else {
+			start = i
+			for i < len(line) && line[i] > ' ' {
+				if line[i] == '=' || line[i] == '"' {
+					return nil, fmt.Errorf("log: logfmt: unexpected %q at offset %d", line[i], i)
+				}
+				i++
+			}
+			value = string(line[start:i])
+		}
+		pairs = append(pairs, key, value)
+	}
+}
+
+// ParseLogfmt decodes a line written by LogfmtFormat back into a Record.
+// The built-in fields are looked for under names, where empty names mean
+// the default ones, and the time is parsed as opts say, so they should
+// match those the line was written with. The caller is returned in
+// CustomCaller and the trace ids in Ctx, see trace.SpanFromContext;
+// every other pair is returned in KeyValues with its value as a string,
+// so formatting the Record with LogfmtFormat writes the line again.
+//
+// The Record is allocated for the caller, who may keep it.
+func ParseLogfmt(line []byte, names RecordKeyNames, opts ...FormatOptions) (*Record, error) {
+	pairs, err := ParseLogfmtPairs(line)
+	if err != nil {
+		return nil, err
+	}
+	o := newFormatOptions(timeFormat, opts)
+	names = names.clone().inherit(defaultRecordKeyNames)
+	r := &Record{KeyNames: names}
+	var span trace.Span
+	for i := 0; i < len(pairs); i += 2 {
+		k, v := pairs[i], pairs[i+1]
+		switch k {
+		case names.Time:
+			if r.Time, err = o.parseTime(v); err != nil {
+				return nil, fmt.Errorf("log: logfmt: bad %s: %v", k, err)
+			}
+			continue
+		case names.Level:
+			if r.Level, err = ParseLevel(v); err != nil {
+				return nil, fmt.Errorf("log: logfmt: bad %s: %v", k, err)
+			}
+			continue
+		case names.Msg:
+			r.Msg = v
+			continue
+		case names.Call:
+			r.CustomCaller = v
+			continue
+		case names.RequestID:
+			span.TraceID = v
+			continue
+		case names.SpanID:
+			span.SpanID = v
+			continue
+		case names.ParentID:
+			span.ParentID = v
+			continue
+		}
+		r.KeyValues = append(r.KeyValues, k, v)
+	}
+	if span != (trace.Span{}) {
+		r.Ctx = trace.NewSpanContext(context.Background(), span)
+	}
+	return r, nil
+}
+
+// parseTime parses a time written by appendTime.
+func (o *formatOptions) parseTime(s string) (time.Time, error) {
+	switch o.timeFormat {
+	case TimeEpoch:
+		sec, frac := s, ""
+		if i := strings.IndexByte(s, '.'); i >= 0 {
+			sec, frac = s[:i], s[i+1:]
+		}
+		n, err := strconv.ParseInt(sec, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		var nsec int64
+		if frac != "" {
+			if len(frac) > 9 {
+				return time.Time{}, fmt.Errorf("too many digits in %q", s)
+			}
+			f, err := strconv.ParseUint(frac, 10, 32)
+			if err != nil {
+				return time.Time{}, err
+			}
+			nsec = int64(f)
+			for i := len(frac); i < 9; i++ {
+				nsec *= 10
+			}
+			if sec[0] == '-' {
+				nsec = -nsec
+			}
+		}
+		return o.in(time.Unix(n, nsec)), nil
+	case TimeEpochMillis, TimeEpochNanos:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if o.timeFormat == TimeEpochMillis {
+			n *= int64(time.Millisecond)
+		}
+		return o.in(time.Unix(0, n)), nil
+	}
+	loc := o.timeLocation
+	if loc == nil {
+		loc = time.Local
+	}
+	return time.ParseInLocation(o.timeFormat, s, loc)
+}
+
+func (o *formatOptions) in(t time.Time) time.Time {
+	if o.timeLocation != nil {
+		return t.In(o.timeLocation)
+	}
+	return t
+}
@@ -0,0 +1,80 @@
+//go:build go1.18
+// +build go1.18
+
+package log
+
+import (
+	"bytes"
+	"testing"
+	"unicode/utf8"
+)
+
+// FuzzLogfmt checks that whatever is logged, ParseLogfmt gives back the
+// key and value LogfmtFormat wrote.
+func FuzzLogfmt(f *testing.F) {
+	for _, seed := range [][2]string{
+		{"k", "v"}, {"", ""}, {"a b", "c d"}, {"=", `"`}, {`\`, `\"`},
+		{"ü", " "}, {"\x00", "\x7f\n\t\r"}, {"\xff", "a\xffb"}, {"k", "\ufffd"},
+	} {
+		f.Add(seed[0], seed[1])
+	}
+	fmtr := KeyNamesFormat(RecordKeyNames{Order: []RecordKey{}}, LogfmtFormat())
+	f.Fuzz(func(t *testing.T, k, v string) {
+		line := fmtr.Format(&Record{KeyValues: []interface{}{k, v}})
+		pairs, err := ParseLogfmtPairs(line)
+		if err != nil {
+			t.Fatalf("%q: %v", line, err)
+		}
+		if len(pairs) != 2 {
+			t.Fatalf("%q: got %q", line, pairs)
+		}
+
+		var key bytes.Buffer
+		appendLogfmtKey(&key, k)
+		if pairs[0] != key.String() {
+			t.Fatalf("%q: key %q, want %q", line, pairs[0], key.String())
+		}
+		for _, r := range pairs[0] {
+			if !logfmtSafe(r) && r != utf8.RuneError {
+				t.Fatalf("%q: key %q holds %q", line, pairs[0], r)
+			}
+		}
+		// each byte of invalid UTF-8 comes back as U+FFFD
+		if want := string([]rune(v)); pairs[1] != want {
+			t.Fatalf("%q: value %q, want %q", line, pairs[1], want)
+		}
+	})
+}
+
+// FuzzLogfmtNonStringKey checks that a key which isn't a string is
+// reported in a value ParseLogfmt gives back whole.
+func FuzzLogfmtNonStringKey(f *testing.F) {
+	for _, seed := range []string{"k", "", "a b", `"`, "\x00\n", "\xff"} {
+		f.Add(seed)
+	}
+	fmtr := KeyNamesFormat(RecordKeyNames{Order: []RecordKey{}}, LogfmtFormat())
+	f.Fuzz(func(t *testing.T, k string) {
+		line := fmtr.Format(&Record{KeyValues: []interface{}{badKey(k), "v"}})
+		pairs, err := ParseLogfmtPairs(line)
+		if err != nil {
+			t.Fatalf("%q: %v", line, err)
+		}
+		want := string([]rune(k + " is not a string key"))
+		if len(pairs) != 2 || pairs[0] != errorKey || pairs[1] != want {
+			t.Fatalf("%q: got %q, want %q", line, pairs, []string{errorKey, want})
+		}
+	})
+}
+
+// FuzzParseLogfmtPairs checks that the parser copes with any input.
+func FuzzParseLogfmtPairs(f *testing.F) {
+	for _, seed := range []string{`a=1 b="x y" c`, `a="é\n"`, `a="`, `=`, "a=\"\\\"\" b=\xff"} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, line []byte) {
+		pairs, err := ParseLogfmtPairs(line)
+		if err == nil && len(pairs)%2 != 0 {
+			t.Fatalf("%q: odd number of keys and values %q", line, pairs)
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/wuzuoliang/log/trace"
+)
+
+func TestLogfmtEncoding(t *testing.T) {
+	t.Parallel()
+
+	l, buf := testFormatter(KeyNamesFormat(RecordKeyNames{Order: []RecordKey{KeyMsg}}, LogfmtFormat()))
+	l.Info("a b", "", 1, "k ey", 2, "k=v", 3, `"q"`, 4, "ü", "ü", "empty", "",
+		"ctl", "a\x00b\x7f", "bad", "a\xffb", "back", `a\b`, "nbsp", "a\u00a0b")
+	want := `msg="a b" _=1 k_ey=2 k_v=3 _q_=4 ü=ü empty="" ctl="a\u0000b\u007f" bad="a\ufffdb" back=a\b nbsp="a` + "\u00a0" + `b"` + "\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestParseLogfmt(t *testing.T) {
+	t.Parallel()
+
+	at := time.Date(2026, 10, 17, 21, 4, 5, 120000000, time.UTC)
+	fmtr := LogfmtFormat(SetTimeFormat(time.RFC3339Nano))
+	l, buf := testFormatter(fmtr)
+	l.SetClock(func() time.Time { return at })
+	ctx := trace.NewSpanContext(context.Background(), trace.Span{TraceID: "r 1", SpanID: "s1"})
+	l.WarnContext(ctx, "disk low", "free", 0.5, "path", "/var/log", "note", "say \"hi\"\n")
+
+	r, err := ParseLogfmt(buf.Bytes(), RecordKeyNames{}, SetTimeFormat(time.RFC3339Nano))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Time.Equal(at) || r.Level != LvlWarn || r.Msg != "disk low" || r.CustomCaller != "logfmt_test.go:33" ||
+		recordTrace(r) != (trace.Span{TraceID: "r 1", SpanID: "s1"}) {
+		t.Fatalf("unexpected record %+v", r)
+	}
+	if want := []interface{}{"free", "0.500", "path", "/var/log", "note", "say \"hi\"\n"}; !reflect.DeepEqual(r.KeyValues, want) {
+		t.Fatalf("got %q, want %q", r.KeyValues, want)
+	}
+	if again := fmtr.Format(r); !bytes.Equal(again, buf.Bytes()) {
+		t.Fatalf("formatting the parsed record gave\n%s\ninstead of\n%s", again, buf.Bytes())
+	}
+}
+
+func TestParseLogfmtPairs(t *testing.T) {
+	t.Parallel()
+
+	pairs, err := ParseLogfmtPairs([]byte(` a=1  b c="x y" d= e=""` + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "1", "b", "", "c", "x y", "d", "", "e", ""}; !reflect.DeepEqual(pairs, want) {
+		t.Fatalf("got %q, want %q", pairs, want)
+	}
+
+	for _, line := range []string{`=1`, `a="x`, `a="x"b`, `a=x=y`, `a=x"`, `a"=1`, `a="\q"`} {
+		if _, err := ParseLogfmtPairs([]byte(line)); err == nil {
+			t.Errorf("%s: no error", line)
+		}
+	}
+}
+
+// badKey is a key which isn't a string and formats with a space and a quote.
+type badKey string
+
+func TestLogfmtNonStringKey(t *testing.T) {
+	t.Parallel()
+
+	l, buf := testFormatter(KeyNamesFormat(RecordKeyNames{Order: []RecordKey{KeyMsg}}, LogfmtFormat()))
+	l.Info("m", 5, "five", badKey(`a "b"`), "ab")
+	want := `msg=m error="5 is not a string key" error="a \"b\" is not a string key"` + "\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("got  %s\nwant %s", got, want)
+	}
+}
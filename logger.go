@@ -2,8 +2,10 @@ package log
 
 import (
 	"context"
-	"os"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unsafe"
 
 	"github.com/go-stack/stack"
 )
@@ -18,56 +20,74 @@ const (
 	locationKey = "location"
 	errorKey    = "error"
 	requestID   = "request_id"
+	spanIDKey   = "span_id"
+	parentIDKey = "parent_id"
 )
 
-// Level 日志级别
-type Level int
-
-const (
-	LvlFatal = iota
-	LvlError
-	LvlWarn
-	LvlInfo
-	LvlDebug
-	LvlTrace
-
-	LvlFatalStr = "fatal"
-	LvlErrorStr = "error"
-	LvlWarnStr  = "warn"
-	LvlInfoStr  = "info"
-	LvlDebugStr = "debug"
-	LvlTraceStr = "trace"
-)
-
-var levelStringMap = map[Level]string{
-	LvlTrace: LvlTraceStr,
-	LvlFatal: LvlFatalStr,
-	LvlError: LvlErrorStr,
-	LvlWarn:  LvlWarnStr,
-	LvlInfo:  LvlInfoStr,
-	LvlDebug: LvlDebugStr,
-}
-
-// Returns the name of a Level
-func (l Level) String() string {
-	levelStr, ok := levelStringMap[l]
-	if !ok {
-		// _ErrLogLevelNotMatch
-		panic("the log level not match")
-	}
-	return levelStr
-}
-
-// A Record is what a Logger asks its handler to write
+// A Record is what a Logger asks its handler to write.
+//
+// Records are pooled: the Record passed to Handler.Log belongs to the
+// caller and is reused once Log returns. A handler which keeps a record
+// beyond that, like ChannelHandler or AsyncHandler, must keep r.Clone()
+// instead of r.
 type Record struct {
 	Time         time.Time
 	Level        Level
 	Msg          string
 	KeyValues    []interface{}
+	Fields       []Field
 	Ctx          context.Context
 	Call         stack.Call
 	CustomCaller string
 	KeyNames     RecordKeyNames
+
+	fieldBuf []Field // Fields 所用的缓冲, 随 Record 一起复用
+}
+
+var recordPool = sync.Pool{
+	New: func() interface{} { return new(Record) },
+}
+
+func newRecord() *Record {
+	return recordPool.Get().(*Record)
+}
+
+// releaseRecord returns r to the pool. r must not be used afterwards.
+func releaseRecord(r *Record) {
+	buf := r.fieldBuf
+	for i := range buf {
+		buf[i] = Field{}
+	}
+	*r = Record{fieldBuf: buf[:0]}
+	recordPool.Put(r)
+}
+
+// setFields sets r.Fields to prefix followed by suffix. Unless there is
+// nothing to add to prefix, they are copied into r's own buffer, which
+// the pool reuses.
+func (r *Record) setFields(prefix, suffix []Field) {
+	if len(suffix) == 0 {
+		r.Fields = prefix[:len(prefix):len(prefix)]
+		return
+	}
+	buf := append(r.fieldBuf[:0], prefix...)
+	buf = append(buf, suffix...)
+	r.fieldBuf = buf
+	r.Fields = buf[:len(buf):len(buf)]
+}
+
+// Clone returns a copy of r which the caller owns and may keep after
+// Handler.Log returns. KeyValues is shared with r, as it isn't reused by
+// the pool; Fields is copied.
+func (r *Record) Clone() *Record {
+	c := newRecord()
+	buf := c.fieldBuf
+	*c = *r
+	c.fieldBuf = buf
+	if len(r.Fields) > 0 {
+		c.setFields(nil, r.Fields)
+	}
+	return c
 }
 
 // RecordKeyNames 日志记录规则字段名
@@ -76,7 +96,22 @@ type RecordKeyNames struct {
 	Msg       string
 	Level     string
 	Call      string
-	RequestID string
+	RequestID string // trace id 字段名
+	SpanID    string
+	ParentID  string
+	// Order lists the built-in fields to write, in order; those left out
+	// aren't written. If nil, each format uses its own order.
+	Order []RecordKey
+}
+
+var defaultRecordKeyNames = RecordKeyNames{
+	Time:      timeKey,
+	Msg:       msgKey,
+	Level:     levelKey,
+	Call:      locationKey,
+	RequestID: requestID,
+	SpanID:    spanIDKey,
+	ParentID:  parentIDKey,
 }
 
 // A Logger writes key/value pairs to a Handler
@@ -90,10 +125,14 @@ type Logger interface {
 	// SetHandler updates the logger to write records to the specified handler.
 	SetHandler(h Handler)
 
-	// Set level value. only level below this can be output
+	// Set level value. only level below this can be output. A logger
+	// whose level was never set inherits its parent's, see Named.
 	SetOutLevel(l Level)
 	GetOutLevel() Level
 
+	// Enabled reports whether a record at the given level would be written.
+	Enabled(level Level) bool
+
 	// Log a message at the given level with context key/value pairs
 	Log(msg string, fields ...interface{})
 	Debug(msg string, fields ...interface{})
@@ -102,62 +141,113 @@ type Logger interface {
 	Error(msg string, fields ...interface{})
 	Fatal(msg string, fields ...interface{})
 
-	// LogContext a message at the given level with context key/value pairs
+	// LogContext a message at the given level with context key/value pairs,
+	// after those attached to ctx with ContextWith
 	LogContext(ctx context.Context, msg string, fields ...interface{})
 	DebugContext(ctx context.Context, msg string, fields ...interface{})
 	InfoContext(ctx context.Context, msg string, fields ...interface{})
 	WarnContext(ctx context.Context, msg string, fields ...interface{})
 	ErrorContext(ctx context.Context, msg string, fields ...interface{})
 	FatalContext(ctx context.Context, msg string, fields ...interface{})
+
+	// LogLevel writes a message at the given level, which may be a custom
+	// level registered with RegisterLevel. LvlFatal exits like Fatal does.
+	LogLevel(level Level, msg string, fields ...interface{})
+	LogLevelContext(ctx context.Context, level Level, msg string, fields ...interface{})
+
+	// LogFields writes a message with typed fields only. Unlike the
+	// methods above it never boxes its arguments, so guarded by Enabled
+	// it doesn't allocate at all for disabled levels. ctx may be nil;
+	// pairs attached to it with ContextWith are written too. LvlFatal
+	// exits like Fatal does.
+	LogFields(ctx context.Context, level Level, msg string, fields ...Field)
+
+	// SetKeyNames sets the keys the built-in fields of this logger's
+	// records are written under, and which of them are written. Empty
+	// names keep their defaults. A logger whose key names were never set
+	// uses its parent's.
+	SetKeyNames(names RecordKeyNames)
+	GetKeyNames() RecordKeyNames
+
+	// SetClock sets the function this logger takes the time of records
+	// from, time.Now by default; tests can use a fixed clock to get
+	// deterministic output. A logger whose clock was never set, or was
+	// set to nil, uses its parent's.
+	SetClock(now func() time.Time)
 }
 
 type logger struct {
+	cache     uint64 // 缓存的有效级别, levelGen<<16 | level
 	KeyValues []interface{}
+	fields    []Field
 	handler   *swapHandler
-	level     Level
+	level     int32          // 自身级别, levelUnset 时继承 parent
+	parent    *logger        // New/Named 的上级, root 为 nil
+	name      string         // Named 的名字
+	keyNames  unsafe.Pointer // *RecordKeyNames, nil 时继承 parent
+	clock     unsafe.Pointer // *func() time.Time, nil 时继承 parent
 }
 
 func (l *logger) write(msg string, level Level, fields []interface{}) {
-	if level <= l.level {
-		l.handler.Log(&Record{
-			Time:      time.Now(),
-			Level:     level,
-			Msg:       msg,
-			KeyValues: newKeyValues(l.KeyValues, fields),
-			Call:      stack.Caller(2),
-			KeyNames: RecordKeyNames{
-				Time:      timeKey,
-				Msg:       msgKey,
-				Level:     levelKey,
-				Call:      locationKey,
-				RequestID: requestID,
-			},
-		})
+	if level.Compare(l.maxLevel(2)) <= 0 {
+		kvs, fs := splitFields(fields)
+		r := newRecord()
+		r.Time = l.now()
+		r.Level = level
+		r.Msg = msg
+		r.KeyValues = newKeyValues(l.KeyValues, kvs)
+		r.setFields(l.fields, fs)
+		r.Call = stack.Caller(2)
+		r.KeyNames = *l.getKeyNames()
+		l.handler.Log(r)
+		releaseRecord(r)
 	}
 }
 
 func (l *logger) writeContext(ctx context.Context, msg string, level Level, fields []interface{}) {
-	if level <= l.level {
-		l.handler.Log(&Record{
-			Ctx:       ctx,
-			Time:      time.Now(),
-			Level:     level,
-			Msg:       msg,
-			KeyValues: newKeyValues(l.KeyValues, fields),
-			Call:      stack.Caller(2),
-			KeyNames: RecordKeyNames{
-				Time:      timeKey,
-				Msg:       msgKey,
-				Level:     levelKey,
-				Call:      locationKey,
-				RequestID: requestID,
-			},
-		})
+	if level.Compare(l.maxLevel(2)) <= 0 {
+		kvs, fs := splitFields(fields)
+		prefix, prefixFields := l.contextPrefix(ctx)
+		r := newRecord()
+		r.Ctx = ctx
+		r.Time = l.now()
+		r.Level = level
+		r.Msg = msg
+		r.KeyValues = newKeyValues(prefix, kvs)
+		r.setFields(prefixFields, fs)
+		r.Call = stack.Caller(2)
+		r.KeyNames = *l.getKeyNames()
+		l.handler.Log(r)
+		releaseRecord(r)
+	}
+}
+
+func (l *logger) writeFields(ctx context.Context, msg string, level Level, fields []Field) {
+	if level.Compare(l.maxLevel(2)) <= 0 {
+		prefix, prefixFields := l.contextPrefix(ctx)
+		r := newRecord()
+		r.Ctx = ctx
+		r.Time = l.now()
+		r.Level = level
+		r.Msg = msg
+		r.KeyValues = newKeyValues(prefix, nil)
+		r.setFields(prefixFields, fields)
+		r.Call = stack.Caller(2)
+		r.KeyNames = *l.getKeyNames()
+		l.handler.Log(r)
+		releaseRecord(r)
 	}
 }
 
 func (l *logger) New(keyValues ...interface{}) Logger {
-	child := &logger{newKeyValues(l.KeyValues, keyValues), new(swapHandler), LvlTrace}
+	kvs, fs := splitFields(keyValues)
+	child := &logger{
+		KeyValues: newKeyValues(l.KeyValues, kvs),
+		fields:    newFields(l.fields, fs),
+		handler:   new(swapHandler),
+		level:     levelUnset,
+		parent:    l,
+	}
 	child.SetHandler(l.handler)
 	return child
 }
@@ -171,13 +261,18 @@ func newKeyValues(prefix []interface{}, suffix []interface{}) []interface{} {
 }
 
 func (l *logger) SetOutLevel(level Level) {
-	if level >= LvlFatal && level <= LvlTrace {
-		l.level = level
+	if level >= LvlFatal && (level <= LvlTrace || level.registered()) {
+		atomic.StoreInt32(&l.level, int32(level))
+		atomic.AddUint64(&levelGen, 1)
 	}
 }
 
 func (l *logger) GetOutLevel() Level {
-	return l.level
+	return l.effectiveLevel()
+}
+
+func (l *logger) Enabled(level Level) bool {
+	return level.Compare(l.maxLevel(1)) <= 0
 }
 
 func (l *logger) Log(msg string, fields ...interface{}) {
@@ -202,7 +297,7 @@ func (l *logger) Error(msg string, fields ...interface{}) {
 
 func (l *logger) Fatal(msg string, fields ...interface{}) {
 	l.write(msg, LvlFatal, fields)
-	os.Exit(1)
+	FlushAndExit(l.handler, 1)
 }
 
 func (l *logger) LogContext(ctx context.Context, msg string, fields ...interface{}) {
@@ -227,7 +322,66 @@ func (l *logger) ErrorContext(ctx context.Context, msg string, fields ...interfa
 
 func (l *logger) FatalContext(ctx context.Context, msg string, fields ...interface{}) {
 	l.writeContext(ctx, msg, LvlFatal, fields)
-	os.Exit(1)
+	FlushAndExit(l.handler, 1)
+}
+
+func (l *logger) LogLevel(level Level, msg string, fields ...interface{}) {
+	l.write(msg, level, fields)
+	if level == LvlFatal {
+		FlushAndExit(l.handler, 1)
+	}
+}
+
+func (l *logger) LogLevelContext(ctx context.Context, level Level, msg string, fields ...interface{}) {
+	l.writeContext(ctx, msg, level, fields)
+	if level == LvlFatal {
+		FlushAndExit(l.handler, 1)
+	}
+}
+
+func (l *logger) LogFields(ctx context.Context, level Level, msg string, fields ...Field) {
+	l.writeFields(ctx, msg, level, fields)
+	if level == LvlFatal {
+		FlushAndExit(l.handler, 1)
+	}
+}
+
+func (l *logger) SetKeyNames(names RecordKeyNames) {
+	names = names.clone().inherit(defaultRecordKeyNames)
+	atomic.StorePointer(&l.keyNames, unsafe.Pointer(&names))
+}
+
+func (l *logger) GetKeyNames() RecordKeyNames {
+	return *l.getKeyNames()
+}
+
+// getKeyNames returns the key names of l or of its nearest ancestor which
+// has them set.
+func (l *logger) getKeyNames() *RecordKeyNames {
+	for n := l; n != nil; n = n.parent {
+		if p := atomic.LoadPointer(&n.keyNames); p != nil {
+			return (*RecordKeyNames)(p)
+		}
+	}
+	return &defaultRecordKeyNames
+}
+
+func (l *logger) SetClock(now func() time.Time) {
+	if now == nil {
+		atomic.StorePointer(&l.clock, nil)
+		return
+	}
+	atomic.StorePointer(&l.clock, unsafe.Pointer(&now))
+}
+
+// now returns the time of l's clock or of its nearest ancestor's.
+func (l *logger) now() time.Time {
+	for n := l; n != nil; n = n.parent {
+		if p := atomic.LoadPointer(&n.clock); p != nil {
+			return (*(*func() time.Time)(p))()
+		}
+	}
+	return time.Now()
 }
 
 func (l *logger) GetHandler() Handler {
@@ -0,0 +1,143 @@
+package lumberjack
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/klauspost/pgzip"
+)
+
+// A Codec compresses backups.
+type Codec interface {
+	// Suffix is added to the names of the backups it compressed, such as
+	// ".gz". A codec with no suffix leaves backups as they are.
+	Suffix() string
+
+	// NewWriter returns a writer which compresses what is written to it
+	// into w. Closing it flushes it without closing w.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+}
+
+// Codecs of RotateOption.CompressCodec.
+const (
+	CodecGzip = "gzip"
+	CodecZstd = "zstd"
+	CodecNone = "none"
+)
+
+// codecSuffixes are the suffixes of the codecs of this package, which
+// backups are recognized by whichever codec compresses new ones.
+var codecSuffixes = []string{compressSuffix, zstdSuffix}
+
+const zstdSuffix = ".zst"
+
+// NewCodec returns the codec a name says, CodecGzip, CodecZstd or
+// CodecNone, compressing at level, its codec's default if 0, with
+// concurrency goroutines if more than 1.
+func NewCodec(name string, level, concurrency int) (Codec, error) {
+	switch strings.ToLower(name) {
+	case "", CodecGzip:
+		if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+			return nil, fmt.Errorf("bad gzip level %d", level)
+		}
+		return GzipCodec{Level: level, Concurrency: concurrency}, nil
+	case CodecZstd:
+		if level < 0 || level > 22 {
+			return nil, fmt.Errorf("bad zstd level %d", level)
+		}
+		return ZstdCodec{Level: level, Concurrency: concurrency}, nil
+	case CodecNone:
+		return IdentityCodec{}, nil
+	}
+	return nil, fmt.Errorf("bad compress codec %q: want %s, %s or %s", name, CodecGzip, CodecZstd, CodecNone)
+}
+
+// GzipCodec compresses backups with gzip into `.gz` files.
+type GzipCodec struct {
+	// Level is a compress/gzip level; 0 means gzip.DefaultCompression.
+	Level int
+	// Concurrency is how many blocks of a backup are compressed at once.
+	// Above 1 it compresses blocks of 1MB in parallel, which is faster
+	// for large backups at the cost of a slightly worse ratio.
+	Concurrency int
+}
+
+// Suffix implements Codec.
+func (c GzipCodec) Suffix() string { return compressSuffix }
+
+// NewWriter implements Codec.
+func (c GzipCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	level := c.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	if c.Concurrency <= 1 {
+		return gzip.NewWriterLevel(w, level)
+	}
+	gz, err := pgzip.NewWriterLevel(w, level)
+	if err != nil {
+		return nil, err
+	}
+	if err := gz.SetConcurrency(1<<20, c.Concurrency); err != nil {
+		return nil, err
+	}
+	return gz, nil
+}
+
+// ZstdCodec compresses backups with Zstandard into `.zst` files, which
+// is faster and smaller than gzip.
+type ZstdCodec struct {
+	// Level is a zstd level from 1 to 22, mapped onto the levels of the
+	// encoder; 0 means its default.
+	Level int
+	// Concurrency is how many goroutines compress a backup; 0 or 1 means
+	// one.
+	Concurrency int
+}
+
+// Suffix implements Codec.
+func (c ZstdCodec) Suffix() string { return zstdSuffix }
+
+// NewWriter implements Codec.
+func (c ZstdCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	concurrency := c.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	opts := []zstd.EOption{zstd.WithEncoderConcurrency(concurrency)}
+	if c.Level > 0 {
+		opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(c.Level)))
+	}
+	return zstd.NewWriter(w, opts...)
+}
+
+// IdentityCodec leaves backups uncompressed, though Compress is set.
+type IdentityCodec struct{}
+
+// Suffix implements Codec.
+func (IdentityCodec) Suffix() string { return "" }
+
+// NewWriter implements Codec.
+func (IdentityCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return nopCloser{w}, nil
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// trimCodecSuffix returns name without the suffix of a codec, and whether
+// it had one.
+func trimCodecSuffix(name string, suffixes []string) (string, bool) {
+	for _, s := range suffixes {
+		if s != "" && strings.HasSuffix(name, s) {
+			return name[:len(name)-len(s)], true
+		}
+	}
+	return name, false
+}
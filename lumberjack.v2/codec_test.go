@@ -0,0 +1,127 @@
+package lumberjack
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestCodecs(t *testing.T) {
+	data := []byte(strings.Repeat("page accessed path=/users user_id=1\n", 100000))
+	gunzip := func(b []byte) ([]byte, error) {
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.ReadAll(r)
+	}
+	unzstd := func(b []byte) ([]byte, error) {
+		r, err := zstd.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return ioutil.ReadAll(r)
+	}
+	tests := []struct {
+		codec  Codec
+		suffix string
+		decode func([]byte) ([]byte, error)
+	}{
+		{GzipCodec{}, ".gz", gunzip},
+		{GzipCodec{Level: gzip.BestSpeed}, ".gz", gunzip},
+		{GzipCodec{Concurrency: 4}, ".gz", gunzip},
+		{ZstdCodec{}, ".zst", unzstd},
+		{ZstdCodec{Level: 19, Concurrency: 4}, ".zst", unzstd},
+		{IdentityCodec{}, "", func(b []byte) ([]byte, error) { return b, nil }},
+	}
+	for _, tt := range tests {
+		equals(tt.suffix, tt.codec.Suffix(), t)
+		var buf bytes.Buffer
+		w, err := tt.codec.NewWriter(&buf)
+		isNil(err, t)
+		_, err = w.Write(data)
+		isNil(err, t)
+		isNil(w.Close(), t)
+		got, err := tt.decode(buf.Bytes())
+		isNil(err, t)
+		assert(bytes.Equal(data, got), t, "%T: data changed", tt.codec)
+	}
+}
+
+func TestNewCodec(t *testing.T) {
+	c, err := NewCodec("", 0, 0)
+	isNil(err, t)
+	equals(GzipCodec{}, c, t)
+	c, err = NewCodec("ZSTD", 3, 2)
+	isNil(err, t)
+	equals(ZstdCodec{Level: 3, Concurrency: 2}, c, t)
+	c, err = NewCodec("none", 0, 0)
+	isNil(err, t)
+	equals(IdentityCodec{}, c, t)
+
+	bad := []struct {
+		name  string
+		level int
+	}{{"lz4", 0}, {"gzip", 10}, {"zstd", 23}}
+	for _, b := range bad {
+		_, err := NewCodec(b.name, b.level, 0)
+		notNil(err, t)
+	}
+}
+
+func TestCompressZstd(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestCompressZstd", t)
+	defer os.RemoveAll(dir)
+
+	// compressed by gzip before the codec changed
+	old := backupFile(dir) + compressSuffix
+	isNil(ioutil.WriteFile(old, []byte("old"), 0644), t)
+	newFakeTime()
+
+	l := &Logger{
+		Filename:     logFile(dir),
+		RotateOption: RotateOption{MaxSize: 3, MaxBackups: 2, Compress: true, CompressCodec: CodecZstd},
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("aa"))
+	isNil(err, t)
+	newFakeTime()
+	_, err = l.Write([]byte("bb"))
+	isNil(err, t)
+	<-time.After(10 * time.Millisecond)
+
+	exists(backupFile(dir)+zstdSuffix, t)
+	notExist(backupFile(dir), t)
+	exists(old, t)
+
+	files, err := l.oldLogFiles()
+	isNil(err, t)
+	equals(2, len(files), t)
+
+	// the gzip backup counts towards MaxBackups
+	newFakeTime()
+	_, err = l.Write([]byte("cc"))
+	isNil(err, t)
+	<-time.After(10 * time.Millisecond)
+	notExist(old, t)
+	fileCount(dir, 3, t)
+}
+
+func TestBadCodec(t *testing.T) {
+	dir := makeTempDir("TestBadCodec", t)
+	defer os.RemoveAll(dir)
+	l := &Logger{Filename: logFile(dir), RotateOption: RotateOption{CompressCodec: "lz4"}}
+	_, err := l.Write([]byte("x"))
+	notNil(err, t)
+}
@@ -0,0 +1,10 @@
+// +build !linux,!darwin,!freebsd,!dragonfly,!windows
+
+package lumberjack
+
+import "errors"
+
+// freeSpace isn't supported here, which turns off MinFreeSpace.
+func freeSpace(dir string) (uint64, error) {
+	return 0, errors.New("free space unknown on this platform")
+}
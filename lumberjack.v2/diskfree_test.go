@@ -0,0 +1,136 @@
+package lumberjack
+
+import (
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMaxTotalSize(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestMaxTotalSize", t)
+	defer os.RemoveAll(dir)
+	l := &Logger{
+		Filename:     logFile(dir),
+		RotateOption: RotateOption{MaxSize: 10, MaxTotalSize: 20},
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("aaaaaaaa"))
+	isNil(err, t)
+	newFakeTime()
+	first := backupFile(dir)
+	_, err = l.Write([]byte("bbbbbbbb"))
+	isNil(err, t)
+	<-time.After(10 * time.Millisecond)
+	existsWithContent(first, []byte("aaaaaaaa"), t)
+
+	// 24 bytes now, the oldest backup has to go
+	newFakeTime()
+	second := backupFile(dir)
+	_, err = l.Write([]byte("cccccccc"))
+	isNil(err, t)
+	<-time.After(10 * time.Millisecond)
+	notExist(first, t)
+	existsWithContent(second, []byte("bbbbbbbb"), t)
+	fileCount(dir, 2, t)
+}
+
+func TestMinFreeSpace(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestMinFreeSpace", t)
+	defer os.RemoveAll(dir)
+
+	// a disk of 40 bytes holding nothing but the logs
+	diskFree = func(string) (uint64, error) {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return 0, err
+		}
+		used := uint64(0)
+		for _, f := range files {
+			used += uint64(f.Size())
+		}
+		return 40 - used, nil
+	}
+	defer func() { diskFree = freeSpace }()
+
+	l := &Logger{
+		Filename:     logFile(dir),
+		RotateOption: RotateOption{MaxSize: 10, MinFreeSpace: 20},
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("aaaaaaaa"))
+	isNil(err, t)
+	newFakeTime()
+	first := backupFile(dir)
+	_, err = l.Write([]byte("bbbbbbbb"))
+	isNil(err, t)
+	<-time.After(10 * time.Millisecond)
+	existsWithContent(first, []byte("aaaaaaaa"), t)
+
+	// 24 bytes leave only 16 free
+	newFakeTime()
+	second := backupFile(dir)
+	_, err = l.Write([]byte("cccccccc"))
+	isNil(err, t)
+	<-time.After(10 * time.Millisecond)
+	notExist(first, t)
+	existsWithContent(second, []byte("bbbbbbbb"), t)
+	existsWithContent(logFile(dir), []byte("cccccccc"), t)
+}
+
+func TestDiskFull(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	var free uint64
+	diskFree = func(string) (uint64, error) { return atomic.LoadUint64(&free), nil }
+	defer func() { diskFree = freeSpace }()
+
+	dir := makeTempDir("TestDiskFull", t)
+	defer os.RemoveAll(dir)
+	l := &Logger{
+		Filename:     logFile(dir),
+		RotateOption: RotateOption{MinFreeSpace: 10, DiskFull: DiskFullStop},
+	}
+	defer l.Close()
+
+	n, err := l.Write([]byte("a"))
+	equals(ErrDiskFull, err, t)
+	equals(0, n, t)
+
+	// written again once there is space, checked a second later
+	atomic.StoreUint64(&free, 100)
+	_, err = l.Write([]byte("b"))
+	equals(ErrDiskFull, err, t)
+	addFakeTime(time.Second)
+	_, err = l.Write([]byte("c"))
+	isNil(err, t)
+	existsWithContent(logFile(dir), []byte("c"), t)
+
+	// writes one in three
+	atomic.StoreUint64(&free, 0)
+	addFakeTime(time.Second)
+	l.DiskFull, l.DiskFullRate = DiskFullSample, 3
+	for _, b := range []string{"1", "2", "3", "4", "5"} {
+		n, err := l.Write([]byte(b))
+		isNil(err, t)
+		equals(1, n, t)
+	}
+	existsWithContent(logFile(dir), []byte("c14"), t)
+}
+
+func TestBadDiskFull(t *testing.T) {
+	dir := makeTempDir("TestBadDiskFull", t)
+	defer os.RemoveAll(dir)
+	l := &Logger{Filename: logFile(dir), RotateOption: RotateOption{DiskFull: "panic"}}
+	_, err := l.Write([]byte("x"))
+	notNil(err, t)
+}
@@ -0,0 +1,15 @@
+// +build linux darwin freebsd dragonfly
+
+package lumberjack
+
+import "syscall"
+
+// freeSpace returns the bytes free for unprivileged users on the file
+// system of dir.
+func freeSpace(dir string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
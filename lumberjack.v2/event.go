@@ -0,0 +1,90 @@
+package lumberjack
+
+import "time"
+
+// EventKind is what happened to a log file or its backups.
+type EventKind string
+
+// Kinds of events.
+const (
+	EventRotated    EventKind = "rotated"
+	EventCompressed EventKind = "compressed"
+	EventDeleted    EventKind = "deleted"
+	EventFailed     EventKind = "failed"
+)
+
+// RotateReason is why a log file was rotated.
+type RotateReason string
+
+// Reasons for rotations.
+const (
+	ReasonSize   RotateReason = "size"   // a write would go over MaxSize
+	ReasonTime   RotateReason = "time"   // a new period or day started
+	ReasonManual RotateReason = "manual" // Rotate was called
+	ReasonOpen   RotateReason = "open"   // the log file couldn't be appended to
+)
+
+// An Event tells OnEvent what the Logger did with a log file or its
+// backups, or failed to do.
+type Event struct {
+	Kind EventKind
+	Time time.Time
+
+	// Filename is the log file rotated, the backup compressed or deleted,
+	// or the file an operation failed on, if any.
+	Filename string
+	// NewName is the backup the log file was rotated to, or the
+	// compressed backup.
+	NewName string
+	// Reason is why the log file was rotated.
+	Reason RotateReason
+	// Op is what failed: "rotate", "write", "compress", "delete" or
+	// "list", listing the backups.
+	Op  string
+	Err error
+}
+
+// emit hands e to OnEvent. Events are delivered in order by a goroutine
+// of their own, so that OnEvent may take its time and call the Logger.
+func (l *Logger) emit(e Event) {
+	fn := l.OnEvent
+	if fn == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = currentTime()
+	}
+	l.eventMu.Lock()
+	l.events = append(l.events, e)
+	if l.delivering {
+		l.eventMu.Unlock()
+		return
+	}
+	l.delivering = true
+	l.eventMu.Unlock()
+	go l.deliver(fn)
+}
+
+// deliver calls fn with the queued events until there are none.
+func (l *Logger) deliver(fn func(Event)) {
+	for {
+		l.eventMu.Lock()
+		events := l.events
+		l.events = nil
+		if len(events) == 0 {
+			l.delivering = false
+			l.eventMu.Unlock()
+			return
+		}
+		l.eventMu.Unlock()
+		for _, e := range events {
+			fn(e)
+		}
+	}
+}
+
+// fail reports that op failed on filename with err, and returns err.
+func (l *Logger) fail(op, filename string, err error) error {
+	l.emit(Event{Kind: EventFailed, Filename: filename, Op: op, Err: err})
+	return err
+}
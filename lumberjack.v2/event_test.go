@@ -0,0 +1,120 @@
+package lumberjack
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+)
+
+// nextEvent returns the next event sent on ch, failing if none comes.
+func nextEvent(ch <-chan Event, t testing.TB) Event {
+	select {
+	case e := <-ch:
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("no event")
+	}
+	return Event{}
+}
+
+func TestEvents(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestEvents", t)
+	defer os.RemoveAll(dir)
+	events := make(chan Event, 16)
+	l := &Logger{
+		Filename: logFile(dir),
+		RotateOption: RotateOption{
+			MaxSize:    10,
+			MaxBackups: 1,
+			Compress:   true,
+			OnEvent:    func(e Event) { events <- e },
+		},
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("aaaaaaaa"))
+	isNil(err, t)
+	newFakeTime()
+	first := backupFile(dir)
+	isNil(l.Rotate(), t)
+
+	e := nextEvent(events, t)
+	equals(EventRotated, e.Kind, t)
+	equals(ReasonManual, e.Reason, t)
+	equals(logFile(dir), e.Filename, t)
+	equals(first, e.NewName, t)
+	equals(fakeTime(), e.Time, t)
+
+	e = nextEvent(events, t)
+	equals(EventCompressed, e.Kind, t)
+	equals(first, e.Filename, t)
+	equals(first+compressSuffix, e.NewName, t)
+
+	_, err = l.Write([]byte("bbbbbbbb"))
+	isNil(err, t)
+	newFakeTime()
+	second := backupFile(dir)
+	_, err = l.Write([]byte("cccccccc"))
+	isNil(err, t)
+
+	e = nextEvent(events, t)
+	equals(EventRotated, e.Kind, t)
+	equals(ReasonSize, e.Reason, t)
+	equals(second, e.NewName, t)
+
+	e = nextEvent(events, t)
+	equals(EventDeleted, e.Kind, t)
+	equals(first+compressSuffix, e.Filename, t)
+
+	e = nextEvent(events, t)
+	equals(EventCompressed, e.Kind, t)
+	equals(second, e.Filename, t)
+}
+
+// failCodec is a Codec which can't compress.
+type failCodec struct{}
+
+func (failCodec) Suffix() string { return ".fail" }
+
+func (failCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return nil, errors.New("no codec")
+}
+
+func TestEventFailed(t *testing.T) {
+	currentTime = fakeTime
+
+	dir := makeTempDir("TestEventFailed", t)
+	defer os.RemoveAll(dir)
+	events := make(chan Event, 16)
+	l := &Logger{
+		Filename: logFile(dir),
+		RotateOption: RotateOption{
+			Compress: true,
+			Codec:    failCodec{},
+			OnEvent:  func(e Event) { events <- e },
+		},
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+	newFakeTime()
+	isNil(l.Rotate(), t)
+
+	e := nextEvent(events, t)
+	equals(EventRotated, e.Kind, t)
+
+	e = nextEvent(events, t)
+	equals(EventFailed, e.Kind, t)
+	equals("compress", e.Op, t)
+	equals(backupFile(dir), e.Filename, t)
+	notNil(e.Err, t)
+
+	// the backup is kept as it is
+	existsWithContent(backupFile(dir), []byte("boo!"), t)
+}
@@ -4,6 +4,7 @@ package lumberjack
 
 import (
 	"os"
+	"sync"
 	"syscall"
 	"testing"
 	"time"
@@ -80,8 +81,8 @@ func TestMaintainOwner(t *testing.T) {
 	err = l.Rotate()
 	isNil(err, t)
 
-	equals(555, fakeFS.files[filename].uid, t)
-	equals(666, fakeFS.files[filename].gid, t)
+	equals(555, fakeFS.file(filename).uid, t)
+	equals(666, fakeFS.file(filename).gid, t)
 }
 
 func TestCompressMaintainMode(t *testing.T) {
@@ -171,8 +172,8 @@ func TestCompressMaintainOwner(t *testing.T) {
 	// a compressed version of the log file should now exist with the correct
 	// owner.
 	filename2 := backupFile(dir)
-	equals(555, fakeFS.files[filename2+compressSuffix].uid, t)
-	equals(666, fakeFS.files[filename2+compressSuffix].gid, t)
+	equals(555, fakeFS.file(filename2+compressSuffix).uid, t)
+	equals(666, fakeFS.file(filename2+compressSuffix).gid, t)
 }
 
 type fakeFile struct {
@@ -181,6 +182,7 @@ type fakeFile struct {
 }
 
 type fakeFS struct {
+	mu    sync.Mutex
 	files map[string]fakeFile
 }
 
@@ -189,10 +191,19 @@ func newFakeFS() *fakeFS {
 }
 
 func (fs *fakeFS) Chown(name string, uid, gid int) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
 	fs.files[name] = fakeFile{uid: uid, gid: gid}
 	return nil
 }
 
+// file returns the owner Chown gave name. The mill may be chowning too.
+func (fs *fakeFS) file(name string) fakeFile {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.files[name]
+}
+
 func (fs *fakeFS) Stat(name string) (os.FileInfo, error) {
 	info, err := os.Stat(name)
 	if err != nil {
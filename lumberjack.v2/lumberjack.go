@@ -22,15 +22,11 @@
 package lumberjack
 
 import (
-	"compress/gzip"
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
 	"path/filepath"
-	"sort"
-	"strings"
 	"sync"
 	"time"
 )
@@ -78,6 +74,32 @@ var _ io.WriteCloser = (*Logger)(nil)
 // time, which may differ from the last time that file was written to.
 //
 // If MaxBackups and MaxAge are both 0, no old log files will be deleted.
+//
+// MaxTotalSize and MinFreeSpace protect small disks: the oldest backups
+// are deleted while the log file and its backups take up more than
+// MaxTotalSize, or while less than MinFreeSpace is free on the file
+// system. If deleting backups can't free enough space, DiskFull decides
+// whether the Logger keeps writing, stops writing or writes a sample of
+// the writes until there is.
+//
+// Rotating On A Schedule
+//
+// If Interval is set, the log file is also rotated whenever a new period
+// starts, by a timer, so a quiet log is rotated on time too. Its backups
+// are named after the start of the period they cover, in the form
+// `name.period.ext`, such as `/var/log/foo/server.2016-11-04-18.log` for
+// the hour from 6pm, with `.1`, `.2`, ... added before the extension for
+// files rotated within the period because of their size.
+//
+// Naming Backups
+//
+// BackupNaming, BackupLayout, BackupDir and BackupDateDir change the names
+// above: the layout of the time, logrotate-style numbers such as
+// `server.log.1` for the newest backup instead of a time, a directory the
+// backups are moved to such as `archive`, and date-partitioned directories
+// under it such as `archive/2016/11/04`. A Namer of one's own can take
+// their place. MaxBackups and MaxAge apply to the backups whatever their
+// names.
 type Logger struct {
 	// Filename is the file to write logs to.  Backup log files will be retained
 	// in the same directory.  It uses <processname>-lumberjack.log in
@@ -91,8 +113,26 @@ type Logger struct {
 	file      *os.File
 	mu        sync.Mutex
 	millCh    chan bool
-	startMill sync.Once
+	millDone  chan struct{}
 	lastDay   int
+
+	schedule    Schedule       // Interval 解析的结果
+	location    *time.Location // 周期及备份名所用时区
+	initErr     error          // 无效的 Interval, TimeZone 等选项
+	initialized bool           // 选项是否已解析
+	periodStart time.Time      // 当前文件所属周期的开始
+	nextRotate  time.Time      // 下一个周期的开始
+	timer       *time.Timer    // 周期切换的定时器
+	timerGen    int            // 过期定时器的回调据此忽略
+	namer       Namer          // 备份的命名方式
+	codec       Codec          // 备份的压缩方式
+	diskChecked time.Time      // 上次检查剩余空间的时间
+	lowSpace    bool           // 剩余空间是否低于 MinFreeSpace
+	lowWrites   int            // 空间不足以来的写入次数, 用于抽样
+	eventMu     sync.Mutex     // 保护 events 与 delivering
+	events      []Event        // 待交给 OnEvent 的事件
+	delivering  bool           // 是否有 goroutine 在交付事件
+	millMu      sync.Mutex     // 轮转时改名与 mill 不同时进行
 }
 
 func NewLogger(fileName string, rotateOption RotateOption) Logger {
@@ -124,14 +164,110 @@ type RotateOption struct {
 	// time.
 	LocalTime bool `json:"localtime" yaml:"localtime"`
 
-	// Compress determines if the rotated log files should be compressed
-	// using gzip.
+	// Compress determines if the rotated log files should be compressed,
+	// using gzip unless CompressCodec says otherwise.
 	Compress bool `json:"compress" yaml:"compress"`
 
 	// Day Rotate log file if system time day changed. The default is use day rotate.
 	DayRotate bool `json:"dayrotate" yaml:"dayrotate"`
+
+	// Interval rotates the log file whenever a new period starts: every
+	// minute, hour, day or week, or as a cron expression says; see
+	// ParseSchedule. It takes the place of DayRotate when set.
+	Interval string `json:"interval" yaml:"interval"`
+
+	// TimeZone is the IANA name of the time zone periods start in and
+	// backups are named in, such as "Asia/Shanghai". If empty, LocalTime
+	// decides between the local time zone and UTC.
+	TimeZone string `json:"timezone" yaml:"timezone"`
+
+	// BackupNaming is how backups are named: "time", the default, after
+	// the time they were rotated, or "number", logrotate-style.
+	BackupNaming string `json:"backupnaming" yaml:"backupnaming"`
+
+	// BackupLayout is the time layout of the names of backups, by default
+	// `2006-01-02T15-04-05.000`, or the Interval's such as `2006-01-02`.
+	BackupLayout string `json:"backuplayout" yaml:"backuplayout"`
+
+	// BackupDir is the directory backups are moved to, relative to the
+	// directory of the log file unless absolute. The default is the
+	// directory of the log file.
+	BackupDir string `json:"backupdir" yaml:"backupdir"`
+
+	// BackupDateDir is the time layout of date-partitioned directories
+	// under BackupDir, such as `2006/01/02`, with "time" naming.
+	BackupDateDir string `json:"backupdatedir" yaml:"backupdatedir"`
+
+	// Namer names backups in place of the options above if set.
+	Namer Namer `json:"-" yaml:"-"`
+
+	// CompressCodec is the codec backups are compressed with: "gzip", the
+	// default, "zstd", or "none" to leave them as they are. Backups
+	// compressed with any of them count as backups whichever is set.
+	CompressCodec string `json:"compresscodec" yaml:"compresscodec"`
+
+	// CompressLevel is the level of the codec, its default if 0.
+	CompressLevel int `json:"compresslevel" yaml:"compresslevel"`
+
+	// CompressConcurrency is how many goroutines compress a backup, so
+	// that a large one is compressed in a fraction of the time. The
+	// default is one.
+	CompressConcurrency int `json:"compressconcurrency" yaml:"compressconcurrency"`
+
+	// Codec compresses backups in place of the options above if set.
+	Codec Codec `json:"-" yaml:"-"`
+
+	// MaxTotalSize is the maximum size in megabytes of the log file and
+	// its backups together, compressed ones at their compressed size. The
+	// oldest backups are deleted to stay within it. The default is no
+	// limit.
+	MaxTotalSize int `json:"maxtotalsize" yaml:"maxtotalsize"`
+
+	// MinFreeSpace is the space in megabytes to leave free on the file
+	// system of the log file, deleting the oldest backups if there is
+	// less. The default is not to check.
+	MinFreeSpace int `json:"minfreespace" yaml:"minfreespace"`
+
+	// DiskFull is what Write does while less than MinFreeSpace is free:
+	// "write", the default, keeps writing, "stop" drops writes with
+	// ErrDiskFull, and "sample" writes one in DiskFullRate writes and
+	// drops the others.
+	DiskFull string `json:"diskfull" yaml:"diskfull"`
+
+	// DiskFullRate is how often "sample" writes, one in 100 writes by
+	// default.
+	DiskFullRate int `json:"diskfullrate" yaml:"diskfullrate"`
+
+	// OnEvent is told when the log file is rotated and when backups are
+	// compressed or deleted, and of the errors doing so or writing, such
+	// as to upload backups, count rotations or report disk errors. It is
+	// called by a goroutine of its own, in the order events happen.
+	OnEvent func(Event) `json:"-" yaml:"-"`
 }
 
+// Policies of RotateOption.DiskFull.
+const (
+	DiskFullWrite  = "write"
+	DiskFullStop   = "stop"
+	DiskFullSample = "sample"
+)
+
+const (
+	// diskCheckInterval is how often Write checks the free space.
+	diskCheckInterval   = time.Second
+	defaultDiskFullRate = 100
+)
+
+// ErrDiskFull is returned by Write when it drops a write because less
+// than MinFreeSpace is free.
+var ErrDiskFull = errors.New("disk nearly full: write dropped")
+
+// Backup naming schemes of RotateOption.BackupNaming.
+const (
+	NamingTime   = "time"
+	NamingNumber = "number"
+)
+
 func DefaultRotateOption() RotateOption {
 	return RotateOption{
 		MaxSize:    defaultMaxSize,
@@ -147,9 +283,15 @@ var (
 	// currentTime exists so it can be mocked out by tests.
 	currentTime = time.Now
 
+	// timeAfterFunc exists so it can be mocked out by tests.
+	timeAfterFunc = time.AfterFunc
+
 	// os_Stat exists so it can be mocked out by tests.
 	os_Stat = os.Stat
 
+	// diskFree exists so it can be mocked out by tests.
+	diskFree = freeSpace
+
 	// megabyte is the conversion factor between MaxSize and bytes.  It is a
 	// variable so tests can mock it out and not need to write megabytes of data
 	// to disk.
@@ -160,6 +302,7 @@ var (
 // than MaxSize, the file is closed, renamed to include a timestamp of the
 // current time, and a new log file is created using the original log file name.
 // If the length of the write is greater than MaxSize, an error is returned.
+// While the disk is nearly full, DiskFull may have it drop the write.
 func (l *Logger) Write(p []byte) (n int, err error) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
@@ -176,27 +319,62 @@ func (l *Logger) Write(p []byte) (n int, err error) {
 			return 0, err
 		}
 	}
-	if l.size+writeLen > l.max() || (l.DayRotate && l.lastDay != time.Now().Day()) {
-		if err := l.rotate(); err != nil {
-			return 0, err
+	if l.diskFull() {
+		switch l.DiskFull {
+		case DiskFullStop:
+			return 0, ErrDiskFull
+		case DiskFullSample:
+			rate := l.DiskFullRate
+			if rate <= 0 {
+				rate = defaultDiskFullRate
+			}
+			l.lowWrites++
+			if rate > 1 && l.lowWrites%rate != 1 {
+				return len(p), nil
+			}
 		}
 	}
+	switch {
+	case l.size+writeLen > l.max():
+		err = l.rotate(ReasonSize)
+	case l.periodOver():
+		err = l.rotate(ReasonTime)
+	}
+	if err != nil {
+		return 0, err
+	}
 
 	n, err = l.file.Write(p)
 	l.size += int64(n)
+	if err != nil {
+		l.fail("write", l.filename(), err)
+	}
 
 	return n, err
 }
 
-// Close implements io.Closer, and closes the current logfile.
+// Sync commits the current contents of the logfile to stable storage.
+func (l *Logger) Sync() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Sync()
+}
+
+// Close implements io.Closer, and closes the current logfile. It waits for
+// the compression and removal of old log files already started.
 func (l *Logger) Close() error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
+	l.stopMill()
 	return l.close()
 }
 
 // close closes the file if it is open.
 func (l *Logger) close() error {
+	l.stopTimer()
 	if l.file == nil {
 		return nil
 	}
@@ -213,18 +391,21 @@ func (l *Logger) close() error {
 func (l *Logger) Rotate() error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	return l.rotate()
+	if err := l.init(); err != nil {
+		return err
+	}
+	return l.rotate(ReasonManual)
 }
 
 // rotate closes the current file, moves it aside with a timestamp in the name,
 // (if it exists), opens a new file with the original filename, and then runs
 // post-rotation processing and removal.
-func (l *Logger) rotate() error {
+func (l *Logger) rotate(reason RotateReason) error {
 	if err := l.close(); err != nil {
-		return err
+		return l.fail("rotate", l.filename(), err)
 	}
-	if err := l.openNew(); err != nil {
-		return err
+	if err := l.openNew(reason); err != nil {
+		return l.fail("rotate", l.filename(), err)
 	}
 	l.mill()
 	return nil
@@ -232,7 +413,7 @@ func (l *Logger) rotate() error {
 
 // openNew opens a new log file for writing, moving any old log file out of the
 // way.  This methods assumes the file has already been closed.
-func (l *Logger) openNew() error {
+func (l *Logger) openNew(reason RotateReason) error {
 	err := os.MkdirAll(l.dir(), 0744)
 	if err != nil {
 		return fmt.Errorf("can't make directories for new logfile: %s", err)
@@ -245,10 +426,11 @@ func (l *Logger) openNew() error {
 		// Copy the mode off the old logfile.
 		mode = info.Mode()
 		// move the existing file
-		newname := backupName(name, l.LocalTime)
-		if err := os.Rename(name, newname); err != nil {
-			return fmt.Errorf("can't rename log file: %s", err)
+		newname, err := l.moveToBackup(name)
+		if err != nil {
+			return err
 		}
+		l.emit(Event{Kind: EventRotated, Filename: name, NewName: newname, Reason: reason})
 
 		// this is a no-op anywhere but linux
 		if err := chown(name, info); err != nil {
@@ -266,56 +448,294 @@ func (l *Logger) openNew() error {
 	l.file = f
 	l.size = 0
 	l.lastDay = time.Now().Day()
+	if l.schedule != nil {
+		l.setPeriod(l.now())
+		l.startTimer()
+	}
 	return nil
 }
 
-// backupName creates a new filename from the given name, inserting a timestamp
-// between the filename and the extension, using the local time if requested
-// (otherwise UTC).
-func backupName(name string, local bool) string {
-	dir := filepath.Dir(name)
-	filename := filepath.Base(name)
-	ext := filepath.Ext(filename)
-	prefix := filename[:len(filename)-len(ext)]
-	t := currentTime()
-	if !local {
-		t = t.UTC()
+// moveToBackup moves the log file name to the backup the namer picks: after
+// the start of its period with an Interval, or else after the current time.
+func (l *Logger) moveToBackup(name string) (string, error) {
+	t := l.now()
+	if l.schedule != nil {
+		t = l.periodStart
 	}
-
-	timestamp := t.Format(backupTimeFormat)
-	return filepath.Join(dir, fmt.Sprintf("%s-%s%s", prefix, timestamp, ext))
+	l.millMu.Lock()
+	defer l.millMu.Unlock()
+	newname, err := l.backupNamer().Backup(name, t)
+	if err != nil {
+		return "", fmt.Errorf("can't name backup: %s", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(newname), 0744); err != nil {
+		return "", fmt.Errorf("can't make directories for backup: %s", err)
+	}
+	if err := os.Rename(name, newname); err != nil {
+		return "", fmt.Errorf("can't rename log file: %s", err)
+	}
+	return newname, nil
 }
 
 // openExistingOrNew opens the logfile if it exists and if the current write
 // would not put it over MaxSize.  If there is no such file or the write would
 // put it over the MaxSize, a new file is created.
 func (l *Logger) openExistingOrNew(writeLen int) error {
+	if err := l.init(); err != nil {
+		return err
+	}
 	l.mill()
 
 	filename := l.filename()
 	info, err := os_Stat(filename)
 	if os.IsNotExist(err) {
-		return l.openNew()
+		return l.openNew(ReasonOpen)
 	}
 	if err != nil {
 		return fmt.Errorf("error getting log file info: %s", err)
 	}
 
+	if l.schedule != nil {
+		// the file belongs to the period it was last written in
+		l.setPeriod(info.ModTime())
+		if l.periodOver() {
+			return l.rotate(ReasonTime)
+		}
+	}
 	if info.Size()+int64(writeLen) >= l.max() {
-		return l.rotate()
+		return l.rotate(ReasonSize)
 	}
 
 	file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0644)
 	if err != nil {
 		// if we fail to open the old log file for some reason, just ignore
 		// it and open a new log file.
-		return l.openNew()
+		return l.openNew(ReasonOpen)
 	}
 	l.file = file
 	l.size = info.Size()
+	if l.schedule != nil {
+		l.startTimer()
+	}
 	return nil
 }
 
+// init parses Interval and TimeZone the first time the Logger opens a
+// file.
+func (l *Logger) init() error {
+	if l.initialized {
+		return l.initErr
+	}
+	l.initialized = true
+	l.location = time.UTC
+	if l.LocalTime {
+		l.location = time.Local
+	}
+	if l.TimeZone != "" {
+		loc, err := time.LoadLocation(l.TimeZone)
+		if err != nil {
+			l.initErr = fmt.Errorf("bad time zone: %s", err)
+			return l.initErr
+		}
+		l.location = loc
+	}
+	if l.Interval != "" {
+		schedule, err := ParseSchedule(l.Interval)
+		if err != nil {
+			l.initErr = err
+			return err
+		}
+		l.schedule = schedule
+	}
+	switch l.BackupNaming {
+	case "", NamingTime, NamingNumber:
+	default:
+		l.initErr = fmt.Errorf("bad backup naming %q: want %s or %s", l.BackupNaming, NamingTime, NamingNumber)
+		return l.initErr
+	}
+	switch l.DiskFull {
+	case "", DiskFullWrite, DiskFullStop, DiskFullSample:
+	default:
+		l.initErr = fmt.Errorf("bad disk full policy %q: want %s, %s or %s", l.DiskFull, DiskFullWrite, DiskFullStop, DiskFullSample)
+		return l.initErr
+	}
+	l.namer = l.newNamer()
+	l.codec = l.Codec
+	if l.codec == nil {
+		codec, err := NewCodec(l.CompressCodec, l.CompressLevel, l.CompressConcurrency)
+		if err != nil {
+			l.initErr = err
+			return err
+		}
+		l.codec = codec
+	}
+	return nil
+}
+
+// newNamer returns the Namer the options ask for.
+func (l *Logger) newNamer() Namer {
+	if l.Namer != nil {
+		return l.Namer
+	}
+	if l.BackupNaming == NamingNumber {
+		return NumberNamer{Dir: l.BackupDir}
+	}
+	n := TimeNamer{Layout: l.BackupLayout, Dir: l.BackupDir, DateDir: l.BackupDateDir}
+	if l.schedule != nil {
+		n.Separator = "."
+		if n.Layout == "" {
+			n.Layout = l.schedule.Layout()
+		}
+	}
+	return n
+}
+
+// backupNamer returns the namer of the Logger, or of its options if it
+// hasn't opened a file yet.
+func (l *Logger) backupNamer() Namer {
+	if l.namer != nil {
+		return l.namer
+	}
+	return l.newNamer()
+}
+
+// backupCodec returns the codec of the Logger, gzip if it hasn't opened
+// a file yet.
+func (l *Logger) backupCodec() Codec {
+	if l.codec != nil {
+		return l.codec
+	}
+	return GzipCodec{}
+}
+
+// backupSuffixes returns the suffixes of compressed backups.
+func (l *Logger) backupSuffixes() []string {
+	suffix := l.backupCodec().Suffix()
+	for _, s := range codecSuffixes {
+		if s == suffix {
+			return codecSuffixes
+		}
+	}
+	return append([]string{suffix}, codecSuffixes...)
+}
+
+// backupLocation returns the time zone the times in names are read in.
+func (l *Logger) backupLocation() *time.Location {
+	switch {
+	case l.location != nil:
+		return l.location
+	case l.LocalTime:
+		return time.Local
+	}
+	return time.UTC
+}
+
+// now returns the current time in the time zone the Logger rotates in.
+func (l *Logger) now() time.Time {
+	if l.location == nil {
+		return currentTime()
+	}
+	return currentTime().In(l.location)
+}
+
+// setPeriod makes the current file belong to the period t is in.
+func (l *Logger) setPeriod(t time.Time) {
+	l.periodStart = l.schedule.Prev(t.In(l.location))
+	l.nextRotate = l.schedule.Next(l.periodStart)
+}
+
+// periodOver reports whether the current file's period, or day with
+// DayRotate, is over.
+func (l *Logger) periodOver() bool {
+	if l.schedule == nil {
+		return l.DayRotate && l.lastDay != time.Now().Day()
+	}
+	return !l.nextRotate.IsZero() && !l.now().Before(l.nextRotate)
+}
+
+// startTimer arranges for the current file to be rotated when its period
+// is over.
+func (l *Logger) startTimer() {
+	l.stopTimer()
+	if l.nextRotate.IsZero() {
+		return
+	}
+	gen := l.timerGen
+	l.timer = timeAfterFunc(l.nextRotate.Sub(l.now()), func() {
+		l.rotateOnSchedule(gen)
+	})
+}
+
+// stopTimer stops the rotation timer. A callback already waiting for the
+// lock sees that its timer is stale.
+func (l *Logger) stopTimer() {
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+	l.timerGen++
+}
+
+// rotateOnSchedule is called by the timer of generation gen when a period
+// is over.
+func (l *Logger) rotateOnSchedule(gen int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if gen != l.timerGen || l.file == nil {
+		return
+	}
+	l.timer = nil
+	switch {
+	case !l.periodOver():
+		// woke up early, by the wall clock
+		l.startTimer()
+	case l.size == 0:
+		// there is nothing to keep
+		l.setPeriod(l.now())
+		l.startTimer()
+	default:
+		// the next Write retries if this fails
+		_ = l.rotate(ReasonTime)
+	}
+}
+
+// diskFull reports whether less than MinFreeSpace is free on the file
+// system of the log file. It checks at most once a second, and has the
+// mill delete backups when there is too little.
+func (l *Logger) diskFull() bool {
+	if l.MinFreeSpace <= 0 {
+		return false
+	}
+	now := currentTime()
+	if !l.diskChecked.IsZero() && now.Sub(l.diskChecked) < diskCheckInterval {
+		return l.lowSpace
+	}
+	l.diskChecked = now
+	free, err := diskFree(l.dir())
+	if err != nil {
+		// what am I going to do, stop logging?
+		l.lowSpace = false
+		return false
+	}
+	wasLow := l.lowSpace
+	l.lowSpace = free < l.minFree()
+	switch {
+	case l.lowSpace && !wasLow && (l.DiskFull == DiskFullStop || l.DiskFull == DiskFullSample):
+		l.fail("write", l.filename(), ErrDiskFull)
+		fallthrough
+	case l.lowSpace:
+		l.mill()
+	case wasLow:
+		l.lowWrites = 0
+	}
+	return l.lowSpace
+}
+
+// minFree returns the bytes to leave free on the file system.
+func (l *Logger) minFree() uint64 {
+	return uint64(l.MinFreeSpace) * uint64(megabyte)
+}
+
 // genFilename generates the name of the logfile from the current time.
 func (l *Logger) filename() string {
 	if l.Filename != "" {
@@ -330,16 +750,37 @@ func (l *Logger) filename() string {
 // files are removed, keeping at most l.MaxBackups files, as long as
 // none of them are older than MaxAge.
 func (l *Logger) millRunOnce() error {
-	if l.MaxBackups == 0 && l.MaxAge == 0 && !l.Compress {
+	if l.MaxBackups == 0 && l.MaxAge == 0 && !l.Compress && l.MaxTotalSize == 0 && l.MinFreeSpace == 0 {
 		return nil
 	}
 
+	compress, err := l.removeOldLogFiles()
+	for _, f := range compress {
+		errCompress := l.compressLogFile(f.path)
+		if errCompress != nil {
+			l.fail("compress", f.path, errCompress)
+		}
+		if err == nil && errCompress != nil {
+			err = errCompress
+		}
+	}
+	return err
+}
+
+// removeOldLogFiles removes the backups retention doesn't keep, and
+// returns those to compress.
+func (l *Logger) removeOldLogFiles() (compress []logInfo, err error) {
+	// a Namer may rename backups when the log file is rotated
+	l.millMu.Lock()
+	defer l.millMu.Unlock()
+
 	files, err := l.oldLogFiles()
 	if err != nil {
-		return err
+		return nil, l.fail("list", l.filename(), err)
 	}
+	suffixes := l.backupSuffixes()
 
-	var compress, remove []logInfo
+	var remove []logInfo
 
 	if l.MaxBackups > 0 && l.MaxBackups < len(files) {
 		preserved := make(map[string]bool)
@@ -347,10 +788,7 @@ func (l *Logger) millRunOnce() error {
 		for _, f := range files {
 			// Only count the uncompressed log file or the
 			// compressed log file, not both.
-			fn := f.Name()
-			if strings.HasSuffix(fn, compressSuffix) {
-				fn = fn[:len(fn)-len(compressSuffix)]
-			}
+			fn, _ := trimCodecSuffix(f.path, suffixes)
 			preserved[fn] = true
 
 			if len(preserved) > l.MaxBackups {
@@ -376,36 +814,75 @@ func (l *Logger) millRunOnce() error {
 		files = remaining
 	}
 
-	if l.Compress {
+	if l.MaxTotalSize > 0 || l.MinFreeSpace > 0 {
+		var removed []logInfo
+		files, removed = l.removeForSpace(files)
+		remove = append(remove, removed...)
+	}
+
+	if l.Compress && l.backupCodec().Suffix() != "" {
 		for _, f := range files {
-			if !strings.HasSuffix(f.Name(), compressSuffix) {
+			if _, ok := trimCodecSuffix(f.path, suffixes); !ok {
 				compress = append(compress, f)
 			}
 		}
 	}
 
 	for _, f := range remove {
-		errRemove := os.Remove(filepath.Join(l.dir(), f.Name()))
+		errRemove := os.Remove(f.path)
+		if errRemove != nil {
+			l.fail("delete", f.path, errRemove)
+		} else {
+			l.emit(Event{Kind: EventDeleted, Filename: f.path})
+		}
 		if err == nil && errRemove != nil {
 			err = errRemove
 		}
 	}
-	for _, f := range compress {
-		fn := filepath.Join(l.dir(), f.Name())
-		errCompress := compressLogFile(fn, fn+compressSuffix)
-		if err == nil && errCompress != nil {
-			err = errCompress
+	return compress, err
+}
+
+// removeForSpace picks the oldest backups to delete to keep within
+// MaxTotalSize and MinFreeSpace, and returns the rest.
+func (l *Logger) removeForSpace(files []logInfo) (remaining, remove []logInfo) {
+	sizes := make([]int64, len(files))
+	var total int64
+	if info, err := os_Stat(l.filename()); err == nil {
+		total = info.Size()
+	}
+	for i, f := range files {
+		if info, err := os_Stat(f.path); err == nil {
+			sizes[i] = info.Size()
+			total += sizes[i]
 		}
 	}
 
-	return err
+	// the bytes to free
+	var excess int64
+	if l.MaxTotalSize > 0 {
+		excess = total - int64(l.MaxTotalSize)*int64(megabyte)
+	}
+	if l.MinFreeSpace > 0 {
+		if free, err := diskFree(l.dir()); err == nil && free < l.minFree() {
+			if short := int64(l.minFree() - free); short > excess {
+				excess = short
+			}
+		}
+	}
+
+	n := len(files)
+	for ; n > 0 && excess > 0; n-- {
+		excess -= sizes[n-1]
+	}
+	return files[:n], files[n:]
 }
 
 // millRun runs in a goroutine to manage post-rotation compression and removal
-// of old log files.
-func (l *Logger) millRun() {
-	for _ = range l.millCh {
-		// what am I going to do, log this?
+// of old log files, until millCh is closed.
+func (l *Logger) millRun(millCh <-chan bool, done chan<- struct{}) {
+	defer close(done)
+	for _ = range millCh {
+		// errors are reported to OnEvent
 		_ = l.millRunOnce()
 	}
 }
@@ -413,60 +890,40 @@ func (l *Logger) millRun() {
 // mill performs post-rotation compression and removal of stale log files,
 // starting the mill goroutine if necessary.
 func (l *Logger) mill() {
-	l.startMill.Do(func() {
+	if l.millCh == nil {
 		l.millCh = make(chan bool, 1)
-		go l.millRun()
-	})
+		l.millDone = make(chan struct{})
+		go l.millRun(l.millCh, l.millDone)
+	}
 	select {
 	case l.millCh <- true:
 	default:
 	}
 }
 
-// oldLogFiles returns the list of backup log files stored in the same
-// directory as the current log file, sorted by ModTime
-func (l *Logger) oldLogFiles() ([]logInfo, error) {
-	files, err := ioutil.ReadDir(l.dir())
-	if err != nil {
-		return nil, fmt.Errorf("can't read log file directory: %s", err)
+// stopMill stops the mill goroutine once it is done with the work asked
+// of it. The next write starts it again.
+func (l *Logger) stopMill() {
+	if l.millCh == nil {
+		return
 	}
-	logFiles := []logInfo{}
-
-	prefix, ext := l.prefixAndExt()
-
-	for _, f := range files {
-		if f.IsDir() {
-			continue
-		}
-		if t, err := l.timeFromName(f.Name(), prefix, ext); err == nil {
-			logFiles = append(logFiles, logInfo{t, f})
-			continue
-		}
-		if t, err := l.timeFromName(f.Name(), prefix, ext+compressSuffix); err == nil {
-			logFiles = append(logFiles, logInfo{t, f})
-			continue
-		}
-		// error parsing means that the suffix at the end was not generated
-		// by lumberjack, and therefore it's not a backup file.
-	}
-
-	sort.Sort(byFormatTime(logFiles))
-
-	return logFiles, nil
+	close(l.millCh)
+	<-l.millDone
+	l.millCh, l.millDone = nil, nil
 }
 
-// timeFromName extracts the formatted time from the filename by stripping off
-// the filename's prefix and extension. This prevents someone's filename from
-// confusing time.parse.
-func (l *Logger) timeFromName(filename, prefix, ext string) (time.Time, error) {
-	if !strings.HasPrefix(filename, prefix) {
-		return time.Time{}, errors.New("mismatched prefix")
+// oldLogFiles returns the list of backup log files the namer finds, newest
+// first.
+func (l *Logger) oldLogFiles() ([]logInfo, error) {
+	backups, err := l.backupNamer().Backups(l.filename(), l.backupLocation(), l.backupSuffixes())
+	if err != nil {
+		return nil, err
 	}
-	if !strings.HasSuffix(filename, ext) {
-		return time.Time{}, errors.New("mismatched extension")
+	logFiles := make([]logInfo, len(backups))
+	for i, b := range backups {
+		logFiles[i] = logInfo{b.Time, b.Path}
 	}
-	ts := filename[len(prefix) : len(filename)-len(ext)]
-	return time.Parse(backupTimeFormat, ts)
+	return logFiles, nil
 }
 
 // max returns the maximum size in bytes of log files before rolling.
@@ -482,23 +939,23 @@ func (l *Logger) dir() string {
 	return filepath.Dir(l.filename())
 }
 
-// prefixAndExt returns the filename part and extension part from the Logger's
-// filename.
-func (l *Logger) prefixAndExt() (prefix, ext string) {
-	filename := filepath.Base(l.filename())
-	ext = filepath.Ext(filename)
-	prefix = filename[:len(filename)-len(ext)] + "-"
-	return prefix, ext
-}
+// compressLogFile compresses the given log file with the codec, removing
+// the uncompressed log file if successful. The file is compressed
+// without holding up rotations; if one renames it meanwhile, the
+// uncompressed file is kept and compressed under its new name next time.
+func (l *Logger) compressLogFile(src string) (err error) {
+	codec := l.backupCodec()
+	dst := src + codec.Suffix()
 
-// compressLogFile compresses the given log file, removing the
-// uncompressed log file if successful.
-func compressLogFile(src, dst string) (err error) {
 	f, err := os.Open(src)
 	if err != nil {
 		return fmt.Errorf("failed to open log file: %v", err)
 	}
 	defer f.Close()
+	opened, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat log file: %v", err)
+	}
 
 	fi, err := os_Stat(src)
 	if err != nil {
@@ -511,13 +968,11 @@ func compressLogFile(src, dst string) (err error) {
 
 	// If this file already exists, we presume it was created by
 	// a previous attempt to compress the log file.
-	gzf, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, fi.Mode())
+	cf, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, fi.Mode())
 	if err != nil {
 		return fmt.Errorf("failed to open compressed log file: %v", err)
 	}
-	defer gzf.Close()
-
-	gz := gzip.NewWriter(gzf)
+	defer cf.Close()
 
 	defer func() {
 		if err != nil {
@@ -526,44 +981,41 @@ func compressLogFile(src, dst string) (err error) {
 		}
 	}()
 
-	if _, err := io.Copy(gz, f); err != nil {
+	w, err := codec.NewWriter(cf)
+	if err != nil {
 		return err
 	}
-	if err := gz.Close(); err != nil {
+	if _, err := io.Copy(w, f); err != nil {
+		w.Close()
 		return err
 	}
-	if err := gzf.Close(); err != nil {
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if err := cf.Close(); err != nil {
 		return err
 	}
-
 	if err := f.Close(); err != nil {
 		return err
 	}
+
+	l.millMu.Lock()
+	defer l.millMu.Unlock()
+	if cur, err := os.Stat(src); err != nil || !os.SameFile(opened, cur) {
+		// renamed by a rotation, along with what was compressed so far
+		return nil
+	}
 	if err := os.Remove(src); err != nil {
 		return err
 	}
+	l.emit(Event{Kind: EventCompressed, Filename: src, NewName: dst})
 
 	return nil
 }
 
-// logInfo is a convenience struct to return the filename and its embedded
-// timestamp.
+// logInfo is a convenience struct to return the path of a backup and its
+// embedded timestamp.
 type logInfo struct {
 	timestamp time.Time
-	os.FileInfo
-}
-
-// byFormatTime sorts by newest time formatted in the name.
-type byFormatTime []logInfo
-
-func (b byFormatTime) Less(i, j int) bool {
-	return b[i].timestamp.After(b[j].timestamp)
-}
-
-func (b byFormatTime) Swap(i, j int) {
-	b[i], b[j] = b[j], b[i]
-}
-
-func (b byFormatTime) Len() int {
-	return len(b)
+	path      string
 }
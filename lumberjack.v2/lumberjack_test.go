@@ -8,6 +8,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
@@ -23,13 +24,31 @@ import (
 
 // Since all the tests uses the time to determine filenames etc, we need to
 // control the wall clock as much as possible, which means having a wall clock
-// that doesn't change unless we want it to.
-var fakeCurrentTime = time.Now()
+// that doesn't change unless we want it to. The mill goroutine reads it
+// too, hence the lock.
+var (
+	fakeTimeMu      sync.Mutex
+	fakeCurrentTime = time.Now()
+)
 
 func fakeTime() time.Time {
+	fakeTimeMu.Lock()
+	defer fakeTimeMu.Unlock()
 	return fakeCurrentTime
 }
 
+// setFakeTime sets the time fakeTime returns.
+func setFakeTime(t time.Time) {
+	fakeTimeMu.Lock()
+	fakeCurrentTime = t
+	fakeTimeMu.Unlock()
+}
+
+// addFakeTime moves the time fakeTime returns on by d.
+func addFakeTime(d time.Duration) {
+	setFakeTime(fakeTime().Add(d))
+}
+
 func TestNewFile(t *testing.T) {
 	currentTime = fakeTime
 
@@ -485,8 +504,8 @@ func TestOldLogFiles(t *testing.T) {
 }
 
 func TestTimeFromName(t *testing.T) {
-	l := &Logger{Filename: "/var/log/myfoo/foo.log"}
-	prefix, ext := l.prefixAndExt()
+	prefix, ext := splitExt(filepath.Base("/var/log/myfoo/foo.log"))
+	prefix += "-"
 
 	tests := []struct {
 		filename string
@@ -500,7 +519,7 @@ func TestTimeFromName(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		got, err := l.timeFromName(test.filename, prefix, ext)
+		got, _, err := parseBackupTime(test.filename, prefix, ext, backupTimeFormat, time.UTC)
 		equals(got, test.want, t)
 		equals(err != nil, test.wantErr, t)
 	}
@@ -802,7 +821,7 @@ func fileCount(dir string, exp int, t testing.TB) {
 
 // newFakeTime sets the fake "current time" to two days later.
 func newFakeTime() {
-	fakeCurrentTime = fakeCurrentTime.Add(time.Hour * 24 * 2)
+	addFakeTime(time.Hour * 24 * 2)
 }
 
 func notExist(path string, t testing.TB) {
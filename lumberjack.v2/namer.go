@@ -0,0 +1,303 @@
+package lumberjack
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Namer decides where the backups of a log file go and finds them
+// again, so that MaxBackups and MaxAge apply to backups of any name.
+type Namer interface {
+	// Backup returns the path the log file filename is moved to when it
+	// is rotated at t, the start of its period with an Interval, in the
+	// time zone the Logger rotates in. It may rename older backups to
+	// make room; the Logger doesn't remove backups meanwhile, and compresses
+	// a backup again under its new name if it was being compressed.
+	// Missing directories are created by the Logger.
+	Backup(filename string, t time.Time) (string, error)
+
+	// Backups returns the backups of filename, newest first, compressed
+	// ones included, whose names end in one of suffixes. Times in names
+	// are read in loc.
+	Backups(filename string, loc *time.Location, suffixes []string) ([]Backup, error)
+}
+
+// A Backup is a backup file found by a Namer.
+type Backup struct {
+	Path string
+	// Time is when the file was rotated; MaxAge is counted from it.
+	Time time.Time
+}
+
+// TimeNamer names backups after the time they were rotated, such as
+// `/var/log/foo/server-2016-11-04T18-30-00.000.log`. With a Layout of its
+// own it adds `.1`, `.2`, ... before the extension if a backup of that name
+// exists.
+type TimeNamer struct {
+	// Layout is the time layout of the names. It defaults to
+	// `2006-01-02T15-04-05.000`.
+	Layout string
+	// Separator goes between the log file's name and the time. It
+	// defaults to `-`.
+	Separator string
+	// Dir is the directory backups are moved to, relative to the log
+	// file's unless absolute, such as `archive`. It defaults to the log
+	// file's directory.
+	Dir string
+	// DateDir is the time layout of date-partitioned directories under
+	// Dir, such as `2006/01/02`.
+	DateDir string
+}
+
+func (n TimeNamer) layout() string {
+	if n.Layout == "" {
+		return backupTimeFormat
+	}
+	return n.Layout
+}
+
+func (n TimeNamer) separator() string {
+	if n.Separator == "" {
+		return "-"
+	}
+	return n.Separator
+}
+
+// Backup implements Namer.
+func (n TimeNamer) Backup(filename string, t time.Time) (string, error) {
+	dir := backupDir(filename, n.Dir)
+	if n.DateDir != "" {
+		dir = filepath.Join(dir, t.Format(n.DateDir))
+	}
+	prefix, ext := splitExt(filepath.Base(filename))
+	stamp := prefix + n.separator() + t.Format(n.layout())
+
+	newname := filepath.Join(dir, stamp+ext)
+	if n.Layout == "" {
+		// a time to the millisecond names one backup, and a compressed
+		// backup of the name is left by an interrupted compression
+		return newname, nil
+	}
+	for seq := 1; backupExists(newname); seq++ {
+		newname = filepath.Join(dir, fmt.Sprintf("%s.%d%s", stamp, seq, ext))
+	}
+	return newname, nil
+}
+
+// Backups implements Namer.
+func (n TimeNamer) Backups(filename string, loc *time.Location, suffixes []string) ([]Backup, error) {
+	root := backupDir(filename, n.Dir)
+	// how deep the date directories go
+	depth := 0
+	if n.DateDir != "" {
+		depth = strings.Count(filepath.Clean(n.DateDir), string(filepath.Separator)) + 1
+	}
+	base := filepath.Base(filename)
+	prefix, ext := splitExt(base)
+	prefix += n.separator()
+
+	type found struct {
+		Backup
+		seq int
+	}
+	var backups []found
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if path == root && os.IsNotExist(err) && n.Dir != "" {
+				// nothing was archived yet
+				return filepath.SkipDir
+			}
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		level := strings.Count(rel, string(filepath.Separator))
+		if info.IsDir() {
+			if path != root && level >= depth {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if level != depth {
+			return nil
+		}
+		name, _ := trimCodecSuffix(info.Name(), suffixes)
+		if t, seq, err := parseBackupTime(name, prefix, ext, n.layout(), loc); err == nil {
+			backups = append(backups, found{Backup{path, t}, seq})
+		}
+		// error parsing means that the suffix at the end was not generated
+		// by lumberjack, and therefore it's not a backup file.
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't read log file directory: %s", err)
+	}
+
+	sort.SliceStable(backups, func(i, j int) bool {
+		if backups[i].Time.Equal(backups[j].Time) {
+			return backups[i].seq > backups[j].seq
+		}
+		return backups[i].Time.After(backups[j].Time)
+	})
+	result := make([]Backup, len(backups))
+	for i, b := range backups {
+		result[i] = b.Backup
+	}
+	return result, nil
+}
+
+// parseBackupTime extracts the formatted time, and the sequence number if
+// any, from the filename by stripping off the filename's prefix and
+// extension. This prevents someone's filename from confusing time.parse.
+func parseBackupTime(filename, prefix, ext, layout string, loc *time.Location) (time.Time, int, error) {
+	if !strings.HasPrefix(filename, prefix) {
+		return time.Time{}, 0, errors.New("mismatched prefix")
+	}
+	if !strings.HasSuffix(filename, ext) || len(filename) < len(prefix)+len(ext) {
+		return time.Time{}, 0, errors.New("mismatched extension")
+	}
+	ts := filename[len(prefix) : len(filename)-len(ext)]
+	t, err := time.ParseInLocation(layout, ts, loc)
+	if err == nil {
+		return t, 0, nil
+	}
+	i := strings.LastIndexByte(ts, '.')
+	if i < 0 {
+		return time.Time{}, 0, err
+	}
+	seq, serr := strconv.Atoi(ts[i+1:])
+	if serr != nil || seq <= 0 {
+		return time.Time{}, 0, err
+	}
+	if t, err = time.ParseInLocation(layout, ts[:i], loc); err != nil {
+		return time.Time{}, 0, err
+	}
+	return t, seq, nil
+}
+
+// NumberNamer names backups logrotate-style, `server.log.1` being the
+// newest, `server.log.2` the one before and so on, renaming the older
+// backups on each rotation. Their times are their modification times.
+type NumberNamer struct {
+	// Dir is the directory backups are moved to, relative to the log
+	// file's unless absolute. It defaults to the log file's directory.
+	Dir string
+}
+
+// Backup implements Namer.
+func (n NumberNamer) Backup(filename string, t time.Time) (string, error) {
+	dir := backupDir(filename, n.Dir)
+	base := filepath.Base(filename)
+	backups, err := n.numbered(dir, base)
+	if err != nil {
+		return "", err
+	}
+	// shift from the oldest, so that no name is taken yet
+	for i := len(backups) - 1; i >= 0; i-- {
+		b := backups[i]
+		newname := filepath.Join(dir, base+"."+strconv.Itoa(b.num+1)+b.suffix)
+		if err := os.Rename(b.path, newname); err != nil {
+			return "", fmt.Errorf("can't rename backup: %s", err)
+		}
+	}
+	return filepath.Join(dir, base+".1"), nil
+}
+
+// Backups implements Namer.
+func (n NumberNamer) Backups(filename string, loc *time.Location, suffixes []string) ([]Backup, error) {
+	backups, err := n.numbered(backupDir(filename, n.Dir), filepath.Base(filename))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Backup, 0, len(backups))
+	for _, b := range backups {
+		if rest, ok := trimCodecSuffix(b.suffix, suffixes); b.suffix == "" || ok && rest == "" {
+			result = append(result, Backup{Path: b.path, Time: b.modTime})
+		}
+	}
+	return result, nil
+}
+
+type numberedBackup struct {
+	path    string
+	num     int
+	suffix  string // 编号之后的部分, 如 .gz
+	modTime time.Time
+}
+
+// numbered returns the files named after base and a number in dir,
+// lowest number first, whatever follows the number, so that Backup
+// renames backups compressed by any codec.
+func (n NumberNamer) numbered(dir, base string) ([]numberedBackup, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) && n.Dir != "" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("can't read log file directory: %s", err)
+	}
+	var backups []numberedBackup
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasPrefix(name, base+".") {
+			continue
+		}
+		rest := name[len(base)+1:]
+		suffix := ""
+		if i := strings.IndexByte(rest, '.'); i >= 0 {
+			rest, suffix = rest[:i], rest[i:]
+		}
+		num, err := strconv.Atoi(rest)
+		if err != nil || num <= 0 {
+			continue
+		}
+		backups = append(backups, numberedBackup{filepath.Join(dir, name), num, suffix, f.ModTime()})
+	}
+	sort.SliceStable(backups, func(i, j int) bool { return backups[i].num < backups[j].num })
+	return backups, nil
+}
+
+// backupDir returns the directory the backups of filename go to.
+func backupDir(filename, dir string) string {
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(filepath.Dir(filename), dir)
+}
+
+// splitExt splits a file name into the part before its extension and
+// the extension.
+func splitExt(filename string) (prefix, ext string) {
+	ext = filepath.Ext(filename)
+	return filename[:len(filename)-len(ext)], ext
+}
+
+// backupExists reports whether a backup of the name exists, compressed or
+// not.
+func backupExists(name string) bool {
+	matches, _ := filepath.Glob(globEscape(name) + "*")
+	for _, m := range matches {
+		if m == name || strings.HasPrefix(m[len(name):], ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// globEscape escapes the characters filepath.Match treats specially.
+func globEscape(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if strings.ContainsRune(`*?[\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,115 @@
+package lumberjack
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNumberNaming(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestNumberNaming", t)
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, "archive")
+	l := &Logger{
+		Filename:     logFile(dir),
+		RotateOption: RotateOption{MaxSize: 3, MaxBackups: 2, BackupNaming: NamingNumber, BackupDir: "archive"},
+	}
+	defer l.Close()
+
+	for _, b := range []string{"aa", "bb", "cc", "dd"} {
+		_, err := l.Write([]byte(b))
+		isNil(err, t)
+		<-time.After(10 * time.Millisecond)
+	}
+	existsWithContent(logFile(dir), []byte("dd"), t)
+	existsWithContent(filepath.Join(archive, "foobar.log.1"), []byte("cc"), t)
+	existsWithContent(filepath.Join(archive, "foobar.log.2"), []byte("bb"), t)
+	notExist(filepath.Join(archive, "foobar.log.3"), t)
+	fileCount(archive, 2, t)
+}
+
+func TestNumberNamingCompress(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestNumberNamingCompress", t)
+	defer os.RemoveAll(dir)
+	l := &Logger{
+		Filename:     logFile(dir),
+		RotateOption: RotateOption{MaxSize: 3, Compress: true, BackupNaming: NamingNumber},
+	}
+	defer l.Close()
+
+	for _, b := range []string{"aa", "bb", "cc"} {
+		_, err := l.Write([]byte(b))
+		isNil(err, t)
+		<-time.After(10 * time.Millisecond)
+	}
+	// compressed backups are renamed along with the others
+	exists(filepath.Join(dir, "foobar.log.1"+compressSuffix), t)
+	exists(filepath.Join(dir, "foobar.log.2"+compressSuffix), t)
+	fileCount(dir, 3, t)
+
+	files, err := l.oldLogFiles()
+	isNil(err, t)
+	equals(2, len(files), t)
+	equals(filepath.Join(dir, "foobar.log.1"+compressSuffix), files[0].path, t)
+}
+
+func TestTimeNaming(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	setFakeTime(time.Date(2026, 10, 17, 13, 20, 0, 0, time.UTC))
+
+	dir := makeTempDir("TestTimeNaming", t)
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, "archive")
+
+	// too old for MaxAge, next to a file which isn't a backup
+	old := filepath.Join(archive, "2026", "09", "foobar-20260901-000000.log")
+	isNil(os.MkdirAll(filepath.Dir(old), 0755), t)
+	isNil(ioutil.WriteFile(old, []byte("old"), 0644), t)
+	notes := filepath.Join(archive, "2026", "09", "notes.txt")
+	isNil(ioutil.WriteFile(notes, []byte("notes"), 0644), t)
+
+	l := &Logger{
+		Filename: logFile(dir),
+		RotateOption: RotateOption{
+			MaxSize:       3,
+			MaxAge:        7,
+			MaxBackups:    1,
+			BackupLayout:  "20060102-150405",
+			BackupDir:     "archive",
+			BackupDateDir: "2006/01",
+		},
+	}
+	defer l.Close()
+
+	for _, b := range []string{"aa", "bb", "cc"} {
+		_, err := l.Write([]byte(b))
+		isNil(err, t)
+	}
+	<-time.After(10 * time.Millisecond)
+
+	// backups rotated within the same second are numbered, and the
+	// numbered one is the newer
+	month := filepath.Join(archive, "2026", "10")
+	notExist(filepath.Join(month, "foobar-20261017-132000.log"), t)
+	existsWithContent(filepath.Join(month, "foobar-20261017-132000.1.log"), []byte("bb"), t)
+	fileCount(month, 1, t)
+	notExist(old, t)
+	exists(notes, t)
+}
+
+func TestBadNaming(t *testing.T) {
+	dir := makeTempDir("TestBadNaming", t)
+	defer os.RemoveAll(dir)
+	l := &Logger{Filename: logFile(dir), RotateOption: RotateOption{BackupNaming: "random"}}
+	_, err := l.Write([]byte("x"))
+	notNil(err, t)
+}
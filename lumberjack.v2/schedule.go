@@ -0,0 +1,262 @@
+package lumberjack
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Schedule says when log files are rotated. The times passed to it are
+// in the time zone the Logger rotates in.
+type Schedule interface {
+	// Next returns the first rotation time after t.
+	Next(t time.Time) time.Time
+	// Prev returns the last rotation time at or before t, the start of
+	// the period t is in.
+	Prev(t time.Time) time.Time
+	// Layout is the time layout the start of a period is written with in
+	// the names of its backups.
+	Layout() string
+}
+
+// Intervals accepted by ParseSchedule besides cron expressions.
+const (
+	Minutely = "minute"
+	Hourly   = "hour"
+	Daily    = "day"
+	Weekly   = "week" // weeks start on Monday
+)
+
+// ParseSchedule returns the Schedule an Interval names: minute, hour,
+// day, week, or a cron expression of five fields,
+//
+//    minute hour day-of-month month day-of-week
+//
+// each of which is *, a number, a range a-b, a step */n or a-b/n, or a
+// comma separated list of those. Days of the week are 0-7, where both 0
+// and 7 are Sunday. As in cron, a day matches if either the day of the
+// month or the day of the week does when both are restricted. For
+// example "0 */6 * * *" rotates every six hours.
+func ParseSchedule(interval string) (Schedule, error) {
+	switch strings.ToLower(strings.TrimSpace(interval)) {
+	case Minutely:
+		return minuteSchedule{}, nil
+	case Hourly:
+		return hourSchedule{}, nil
+	case Daily:
+		return daySchedule{}, nil
+	case Weekly:
+		return weekSchedule{}, nil
+	}
+	c, err := parseCron(interval)
+	if err != nil {
+		return nil, err
+	}
+	if c.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("cron expression %q never matches", interval)
+	}
+	return c, nil
+}
+
+// nextAfter steps from the period start p by step until it is after t,
+// which copes with hours repeated when daylight saving time ends.
+func nextAfter(p, t time.Time, step func(time.Time) time.Time) time.Time {
+	for !p.After(t) {
+		p = step(p)
+	}
+	return p
+}
+
+type minuteSchedule struct{}
+
+func (minuteSchedule) Prev(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, t.Location())
+}
+
+func (s minuteSchedule) Next(t time.Time) time.Time {
+	return nextAfter(s.Prev(t), t, func(p time.Time) time.Time { return p.Add(time.Minute) })
+}
+
+func (minuteSchedule) Layout() string { return "2006-01-02-15-04" }
+
+type hourSchedule struct{}
+
+func (hourSchedule) Prev(t time.Time) time.Time {
+	p := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	if p.After(t) {
+		// the second of two hours with the same wall clock time
+		p = p.Add(time.Hour)
+	}
+	return p
+}
+
+func (s hourSchedule) Next(t time.Time) time.Time {
+	return nextAfter(s.Prev(t), t, func(p time.Time) time.Time { return p.Add(time.Hour) })
+}
+
+func (hourSchedule) Layout() string { return "2006-01-02-15" }
+
+type daySchedule struct{}
+
+func (daySchedule) Prev(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func (s daySchedule) Next(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+}
+
+func (daySchedule) Layout() string { return "2006-01-02" }
+
+type weekSchedule struct{}
+
+func (weekSchedule) Prev(t time.Time) time.Time {
+	monday := t.Day() - (int(t.Weekday())+6)%7
+	return time.Date(t.Year(), t.Month(), monday, 0, 0, 0, 0, t.Location())
+}
+
+func (s weekSchedule) Next(t time.Time) time.Time {
+	p := s.Prev(t)
+	return time.Date(p.Year(), p.Month(), p.Day()+7, 0, 0, 0, 0, p.Location())
+}
+
+func (weekSchedule) Layout() string { return "2006-01-02" }
+
+// cronSchedule is a parsed cron expression; bit i of a field is set if
+// the field matches i.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+// cronSearchLimit bounds the steps Next and Prev take looking for a
+// match, about five years.
+const cronSearchLimit = 5 * (366 + 24 + 60 + 12)
+
+func parseCron(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("bad interval %q: want minute, hour, day, week or a cron expression of 5 fields", expr)
+	}
+	c := new(cronSchedule)
+	var err error
+	if c.minute, _, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if c.hour, _, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if c.dom, c.domStar, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if c.month, _, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if c.dow, c.dowStar, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	return c, nil
+}
+
+// parseCronField parses one field of a cron expression whose values run
+// from min to max. star reports whether it starts with *, which cron
+// takes as unrestricted when matching days.
+func parseCronField(field string, min, max int) (bits uint64, star bool, err error) {
+	for _, part := range strings.Split(field, ",") {
+		lo, hi, step := min, max, 1
+		rng := part
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			rng = part[:i]
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, false, fmt.Errorf("bad step in cron field %q", field)
+			}
+		}
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			i := strings.IndexByte(rng, '-')
+			if lo, err = strconv.Atoi(rng[:i]); err != nil {
+				return 0, false, fmt.Errorf("bad range in cron field %q", field)
+			}
+			if hi, err = strconv.Atoi(rng[i+1:]); err != nil {
+				return 0, false, fmt.Errorf("bad range in cron field %q", field)
+			}
+		default:
+			if lo, err = strconv.Atoi(rng); err != nil {
+				return 0, false, fmt.Errorf("bad value in cron field %q", field)
+			}
+			hi = lo
+			if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, false, fmt.Errorf("cron field %q out of range %d-%d", field, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, strings.HasPrefix(field, "*"), nil
+}
+
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next returns the first matching minute after t, or the zero time if
+// there is none within about five years.
+func (c *cronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, loc)
+	for i := 0; i < cronSearchLimit; i++ {
+		y, m, d := t.Date()
+		switch {
+		case c.month&(1<<uint(m)) == 0:
+			t = time.Date(y, m+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(t):
+			t = time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(y, m, d, t.Hour()+1, 0, 0, 0, loc)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = time.Date(y, m, d, t.Hour(), t.Minute()+1, 0, 0, loc)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// Prev returns the last matching minute at or before t, or the zero time
+// if there is none within about five years.
+func (c *cronSchedule) Prev(t time.Time) time.Time {
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+	for i := 0; i < cronSearchLimit; i++ {
+		y, m, d := t.Date()
+		switch {
+		case c.month&(1<<uint(m)) == 0:
+			t = time.Date(y, m, 1, 0, -1, 0, 0, loc)
+		case !c.dayMatches(t):
+			t = time.Date(y, m, d, 0, -1, 0, 0, loc)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(y, m, d, t.Hour(), -1, 0, 0, loc)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = time.Date(y, m, d, t.Hour(), t.Minute()-1, 0, 0, loc)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (c *cronSchedule) Layout() string { return "2006-01-02-15-04" }
@@ -0,0 +1,135 @@
+package lumberjack
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	// Saturday
+	at := time.Date(2026, 10, 17, 13, 20, 5, 0, time.UTC)
+	tests := []struct {
+		interval   string
+		prev, next time.Time
+	}{
+		{"minute", time.Date(2026, 10, 17, 13, 20, 0, 0, time.UTC), time.Date(2026, 10, 17, 13, 21, 0, 0, time.UTC)},
+		{"Hour", time.Date(2026, 10, 17, 13, 0, 0, 0, time.UTC), time.Date(2026, 10, 17, 14, 0, 0, 0, time.UTC)},
+		{"day", time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{"week", time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 */6 * * *", time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC), time.Date(2026, 10, 17, 18, 0, 0, 0, time.UTC)},
+		{"30 9 * * 1-5", time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC), time.Date(2026, 10, 19, 9, 30, 0, 0, time.UTC)},
+		// the 1st of the month or a Sunday
+		{"0 0 1 * 7", time.Date(2026, 10, 11, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{"15,45 8-10/2 * 1,6 *", time.Date(2026, 6, 30, 10, 45, 0, 0, time.UTC), time.Date(2027, 1, 1, 8, 15, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.interval)
+		isNil(err, t)
+		equals(tt.prev, s.Prev(at), t)
+		equals(tt.next, s.Next(at), t)
+	}
+
+	for _, interval := range []string{"fortnight", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "0 0 31 2 *"} {
+		_, err := ParseSchedule(interval)
+		notNil(err, t)
+	}
+}
+
+func TestIntervalRotation(t *testing.T) {
+	if _, err := time.LoadLocation("Asia/Shanghai"); err != nil {
+		t.Skip(err)
+	}
+	currentTime = fakeTime
+	megabyte = 1
+	setFakeTime(time.Date(2026, 10, 17, 13, 20, 0, 0, time.UTC))
+
+	var fire func()
+	var wait time.Duration
+	timeAfterFunc = func(d time.Duration, f func()) *time.Timer {
+		wait, fire = d, f
+		return time.AfterFunc(time.Hour, func() {})
+	}
+	defer func() { timeAfterFunc = time.AfterFunc }()
+
+	dir := makeTempDir("TestIntervalRotation", t)
+	defer os.RemoveAll(dir)
+	l := &Logger{
+		Filename:     logFile(dir),
+		RotateOption: RotateOption{MaxSize: 3, MaxBackups: 2, Interval: "hour", TimeZone: "Asia/Shanghai"},
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("a"))
+	isNil(err, t)
+	equals(40*time.Minute, wait, t)
+
+	// the timer rotates a quiet log, naming the backup after its hour in
+	// the Logger's time zone
+	setFakeTime(time.Date(2026, 10, 17, 14, 0, 0, 0, time.UTC))
+	fire()
+	existsWithContent(filepath.Join(dir, "foobar.2026-10-17-21.log"), []byte("a"), t)
+	existsWithContent(logFile(dir), []byte{}, t)
+	equals(time.Hour, wait, t)
+
+	// an empty file isn't rotated
+	addFakeTime(time.Hour)
+	fire()
+	fileCount(dir, 2, t)
+
+	// rotations within a period because of the size are numbered
+	for _, b := range []string{"bb", "cc", "dd"} {
+		_, err = l.Write([]byte(b))
+		isNil(err, t)
+	}
+	existsWithContent(filepath.Join(dir, "foobar.2026-10-17-23.log"), []byte("bb"), t)
+	existsWithContent(filepath.Join(dir, "foobar.2026-10-17-23.1.log"), []byte("cc"), t)
+	existsWithContent(logFile(dir), []byte("dd"), t)
+
+	// retention counts the numbered backups as newer
+	<-time.After(10 * time.Millisecond)
+	notExist(filepath.Join(dir, "foobar.2026-10-17-21.log"), t)
+	fileCount(dir, 3, t)
+
+	// a timer stopped by Close does nothing
+	isNil(l.Close(), t)
+	addFakeTime(time.Hour)
+	fire()
+	fileCount(dir, 3, t)
+}
+
+func TestIntervalStaleFile(t *testing.T) {
+	currentTime = fakeTime
+	setFakeTime(time.Date(2026, 10, 17, 13, 20, 0, 0, time.UTC))
+	timeAfterFunc = func(d time.Duration, f func()) *time.Timer {
+		return time.AfterFunc(time.Hour, func() {})
+	}
+	defer func() { timeAfterFunc = time.AfterFunc }()
+
+	dir := makeTempDir("TestIntervalStaleFile", t)
+	defer os.RemoveAll(dir)
+	filename := logFile(dir)
+	isNil(ioutil.WriteFile(filename, []byte("old"), 0644), t)
+	yesterday := fakeTime().Add(-24 * time.Hour)
+	isNil(os.Chtimes(filename, yesterday, yesterday), t)
+
+	l := &Logger{Filename: filename, RotateOption: RotateOption{Interval: "day"}}
+	defer l.Close()
+	_, err := l.Write([]byte("new"))
+	isNil(err, t)
+	existsWithContent(filepath.Join(dir, "foobar.2026-10-16.log"), []byte("old"), t)
+	existsWithContent(filename, []byte("new"), t)
+}
+
+func TestBadInterval(t *testing.T) {
+	dir := makeTempDir("TestBadInterval", t)
+	defer os.RemoveAll(dir)
+	for _, opt := range []RotateOption{{Interval: "sometimes"}, {TimeZone: "Mars/Olympus"}} {
+		l := &Logger{Filename: logFile(dir), RotateOption: opt}
+		_, err := l.Write([]byte("x"))
+		notNil(err, t)
+		notNil(l.Rotate(), t)
+	}
+}
@@ -0,0 +1,281 @@
+package log
+
+import (
+	"fmt"
+	"path"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// loggerKey is the key under which a named logger writes its name.
+const loggerKey = "logger"
+
+// levelUnset marks a logger whose level is inherited from its parent.
+const levelUnset = -1 << 31
+
+var (
+	namedMu      sync.Mutex
+	namedLoggers = map[string]*logger{}
+
+	// levelGen is bumped whenever a level or a level rule changes, which
+	// invalidates every logger's cached effective level.
+	levelGen uint64 = 1
+)
+
+// Named returns the logger registered under name, creating it on first
+// use. Names are dot separated paths: the parent of "db.pool" is "db",
+// whose parent is the root logger. A named logger writes its name under
+// the "logger" key and uses its parent's handler.
+//
+// A named logger's level is inherited from its nearest ancestor whose
+// level has been set, either with SetOutLevel or by a rule given to
+// SetLevelRules, so
+//
+//     log.Named("db").SetOutLevel(log.LvlDebug)
+//
+// turns on debug output for "db.pool" too. Loggers made with New
+// inherit their parent's level the same way.
+func Named(name string) Logger {
+	if name == "" {
+		return root
+	}
+	namedMu.Lock()
+	defer namedMu.Unlock()
+	return named(name)
+}
+
+// named returns the logger for name, creating it and its ancestors.
+// namedMu must be held.
+func named(name string) *logger {
+	if l, ok := namedLoggers[name]; ok {
+		return l
+	}
+	parent := root
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		parent = named(name[:i])
+	}
+	l := &logger{
+		KeyValues: newKeyValues(root.KeyValues, []interface{}{loggerKey, name}),
+		handler:   new(swapHandler),
+		level:     levelUnset,
+		parent:    parent,
+		name:      name,
+	}
+	l.SetHandler(parent.handler)
+	namedLoggers[name] = l
+	return l
+}
+
+// LoggerNames returns the names of all named loggers, sorted.
+func LoggerNames() []string {
+	namedMu.Lock()
+	defer namedMu.Unlock()
+	names := make([]string, 0, len(namedLoggers))
+	for name := range namedLoggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// levelRule sets the level of the loggers or source files matching pattern.
+type levelRule struct {
+	pattern string
+	level   Level
+	file    bool // 匹配源文件而非 logger 名
+}
+
+type levelRules struct {
+	spec    string
+	rules   []levelRule
+	hasFile bool
+}
+
+var _levelRulesPtr unsafe.Pointer = unsafe.Pointer(&levelRules{}) // *levelRules
+
+func getLevelRules() *levelRules {
+	return (*levelRules)(atomic.LoadPointer(&_levelRulesPtr))
+}
+
+// SetLevelRules replaces the level rules with those in spec, a comma
+// separated list of pattern=level items in the style of glog's -vmodule:
+//
+//     log.SetLevelRules("handler*.go=debug,db/*=trace,cache.*=warn")
+//
+// A pattern ending in ".go" is matched against the base name of the
+// source file of the log call, and a pattern containing a slash against
+// as many trailing elements of its path, so "db/*" covers every file in
+// a directory named db. Any other pattern is matched against logger
+// names; a named logger without a matching rule or level of its own
+// inherits its parent's. Patterns use path.Match syntax, and the first
+// matching rule wins. File rules take precedence over logger levels.
+//
+// An empty spec removes all rules. SetLevelRules may be called at any
+// time, for example from an admin endpoint.
+func SetLevelRules(spec string) error {
+	rules, err := parseLevelRules(spec)
+	if err != nil {
+		return err
+	}
+	atomic.StorePointer(&_levelRulesPtr, unsafe.Pointer(rules))
+	atomic.AddUint64(&levelGen, 1)
+	return nil
+}
+
+// CheckLevelRules reports whether SetLevelRules would accept spec,
+// without applying it.
+func CheckLevelRules(spec string) error {
+	_, err := parseLevelRules(spec)
+	return err
+}
+
+func parseLevelRules(spec string) (*levelRules, error) {
+	rules := &levelRules{spec: spec}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		i := strings.LastIndexByte(item, '=')
+		if i <= 0 {
+			return nil, fmt.Errorf("log: bad level rule %q", item)
+		}
+		pattern, name := strings.TrimSpace(item[:i]), strings.TrimSpace(item[i+1:])
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("log: bad level rule %q: %v", item, err)
+		}
+		level, err := ParseLevel(name)
+		if err != nil {
+			return nil, fmt.Errorf("log: bad level rule %q: unknown level", item)
+		}
+		file := strings.HasSuffix(pattern, ".go") || strings.Contains(pattern, "/")
+		rules.rules = append(rules.rules, levelRule{pattern: pattern, level: level, file: file})
+		rules.hasFile = rules.hasFile || file
+	}
+	return rules, nil
+}
+
+// LevelRules returns the spec last given to SetLevelRules.
+func LevelRules() string {
+	return getLevelRules().spec
+}
+
+func (rs *levelRules) nameLevel(name string) (Level, bool) {
+	for _, r := range rs.rules {
+		if r.file {
+			continue
+		}
+		if ok, _ := path.Match(r.pattern, name); ok {
+			return r.level, true
+		}
+	}
+	return 0, false
+}
+
+func (rs *levelRules) fileLevel(file string) (Level, bool) {
+	for _, r := range rs.rules {
+		if !r.file {
+			continue
+		}
+		target := path.Base(file)
+		if n := strings.Count(r.pattern, "/"); n > 0 {
+			target = file
+			for i := len(file) - 1; i >= 0; i-- {
+				if file[i] == '/' {
+					if n == 0 {
+						target = file[i+1:]
+						break
+					}
+					n--
+				}
+			}
+		}
+		if ok, _ := path.Match(r.pattern, target); ok {
+			return r.level, true
+		}
+	}
+	return 0, false
+}
+
+// effectiveLevel returns the level of l or of its nearest ancestor with
+// a level set or a rule matching its name, caching the result until the
+// next change.
+func (l *logger) effectiveLevel() Level {
+	gen := atomic.LoadUint64(&levelGen)
+	if c := atomic.LoadUint64(&l.cache); c>>16 == gen {
+		return Level(int16(c))
+	}
+
+	rules := getLevelRules()
+	level := Level(LvlTrace)
+	for n := l; n != nil; n = n.parent {
+		if lvl := atomic.LoadInt32(&n.level); lvl != levelUnset {
+			level = Level(lvl)
+			break
+		}
+		if n.name == "" {
+			continue
+		}
+		if lvl, ok := rules.nameLevel(n.name); ok {
+			level = lvl
+			break
+		}
+	}
+	atomic.StoreUint64(&l.cache, gen<<16|uint64(uint16(level)))
+	return level
+}
+
+// maxLevel returns the most verbose level l writes at the call site
+// depth frames above the caller of maxLevel.
+func (l *logger) maxLevel(depth int) Level {
+	level := l.effectiveLevel()
+	if rules := getLevelRules(); rules.hasFile {
+		var pcs [1]uintptr
+		if runtime.Callers(depth+2, pcs[:]) == 1 {
+			if lvl, ok := fileLevels.get(pcs[0], rules); ok {
+				level = lvl
+			}
+		}
+	}
+	return level
+}
+
+// fileLevels caches which file rule applies to each call site.
+var fileLevels = &pcLevels{m: map[uintptr]pcLevel{}}
+
+type pcLevel struct {
+	level Level
+	ok    bool
+}
+
+type pcLevels struct {
+	mu    sync.RWMutex
+	rules *levelRules
+	m     map[uintptr]pcLevel
+}
+
+func (c *pcLevels) get(pc uintptr, rules *levelRules) (Level, bool) {
+	c.mu.RLock()
+	e, ok := c.m[pc]
+	stale := c.rules != rules
+	c.mu.RUnlock()
+	if ok && !stale {
+		return e.level, e.ok
+	}
+
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	e.level, e.ok = rules.fileLevel(frame.File)
+
+	c.mu.Lock()
+	if c.rules != rules {
+		c.rules = rules
+		c.m = map[uintptr]pcLevel{}
+	}
+	c.m[pc] = e
+	c.mu.Unlock()
+	return e.level, e.ok
+}
@@ -0,0 +1,105 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func namedLogger(name string) (Logger, *bytes.Buffer) {
+	l := Named(name)
+	var buf bytes.Buffer
+	l.SetHandler(StreamHandler(&buf, LogfmtFormat()))
+	return l, &buf
+}
+
+// testRuns counts the runs of tests which need logger names of their own,
+// since named loggers outlive a test and go test -count=2 runs it again.
+var testRuns uint32
+
+func TestNamedInheritsLevel(t *testing.T) {
+	t.Parallel()
+
+	name := fmt.Sprintf("inherit%d", atomic.AddUint32(&testRuns, 1))
+	parent, _ := namedLogger(name)
+	child, buf := namedLogger(name + ".pool")
+	if Named(name+".pool") != child {
+		t.Fatalf("Named didn't return the registered logger")
+	}
+
+	parent.SetOutLevel(LvlInfo)
+	sub := child.New("conn", 1)
+	child.Debug("hidden")
+	sub.Debug("hidden")
+	if buf.Len() != 0 {
+		t.Fatalf("debug record written below an info parent: %q", buf.String())
+	}
+
+	parent.SetOutLevel(LvlDebug)
+	sub.Debug("shown")
+	if !strings.Contains(buf.String(), "logger="+name+".pool conn=1") {
+		t.Fatalf("child didn't pick up the parent's new level: %q", buf.String())
+	}
+
+	buf.Reset()
+	child.SetOutLevel(LvlError)
+	sub.Warn("hidden")
+	if buf.Len() != 0 || parent.GetOutLevel() != LvlDebug {
+		t.Fatalf("own level didn't override the parent's: %q", buf.String())
+	}
+}
+
+func TestLevelRules(t *testing.T) {
+	defer SetLevelRules("")
+
+	named, buf := namedLogger("rules.db.pool")
+	named.SetOutLevel(LvlWarn)
+	other, otherBuf := namedLogger("rules.cache")
+
+	if err := SetLevelRules("rules.cache=error, rules.db*=trace"); err != nil {
+		t.Fatal(err)
+	}
+	other.Warn("hidden")
+	if otherBuf.Len() != 0 {
+		t.Fatalf("name rule ignored: %q", otherBuf.String())
+	}
+	named.Info("hidden")
+	if buf.Len() != 0 {
+		t.Fatalf("name rule overrode the logger's own level: %q", buf.String())
+	}
+
+	if err := SetLevelRules("*/named_test.go=debug"); err != nil {
+		t.Fatal(err)
+	}
+	named.Debug("shown")
+	if !strings.Contains(buf.String(), "shown") {
+		t.Fatalf("file rule ignored: %q", buf.String())
+	}
+	if !named.Enabled(LvlDebug) || named.GetOutLevel() != LvlWarn {
+		t.Fatalf("Enabled doesn't apply file rules")
+	}
+
+	if err := SetLevelRules("log_test.go=debug"); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	named.Debug("hidden")
+	if buf.Len() != 0 {
+		t.Fatalf("file rule for another file applied: %q", buf.String())
+	}
+	if LevelRules() != "log_test.go=debug" {
+		t.Fatalf("got rules %q", LevelRules())
+	}
+}
+
+func TestLevelRulesErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, spec := range []string{"db", "=debug", "db=loud", "[=debug"} {
+		if err := SetLevelRules(spec); err == nil {
+			t.Errorf("expected an error for %q", spec)
+		}
+	}
+}
@@ -0,0 +1,386 @@
+package log
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ConnState is the connection state of a NetReconnect handler.
+type ConnState int32
+
+const (
+	NetDisconnected ConnState = iota
+	NetConnected
+	NetClosed
+)
+
+func (s ConnState) String() string {
+	switch s {
+	case NetDisconnected:
+		return "disconnected"
+	case NetConnected:
+		return "connected"
+	case NetClosed:
+		return "closed"
+	}
+	return fmt.Sprintf("ConnState(%d)", int32(s))
+}
+
+// ErrNetDisconnected is returned by a NetReconnect handler which is not
+// connected and has no buffer to hold the record.
+var ErrNetDisconnected = errors.New("log: network handler disconnected")
+
+// netOptions storage network handler parameters
+type netOptions struct {
+	tlsConfig    *tls.Config                      // 非空时使用 TLS 连接
+	dialTimeout  time.Duration                    // 建立连接超时
+	writeTimeout time.Duration                    // 单次写超时
+	minBackoff   time.Duration                    // 重连初始间隔
+	maxBackoff   time.Duration                    // 重连最大间隔
+	bufferSize   int                              // 断线期间最多缓存的记录数
+	stateHook    func(state ConnState, err error) // 连接状态变化回调
+}
+
+var _defaultNetOptions = netOptions{
+	dialTimeout:  5 * time.Second,
+	writeTimeout: 5 * time.Second,
+	minBackoff:   100 * time.Millisecond,
+	maxBackoff:   30 * time.Second,
+	bufferSize:   1024,
+}
+
+type NetOptions func(*netOptions)
+
+// SetNetTLS makes the handler connect over TLS with the given config.
+func SetNetTLS(config *tls.Config) NetOptions {
+	return func(o *netOptions) {
+		o.tlsConfig = config
+	}
+}
+
+// SetNetDialTimeout bounds each connection attempt.
+func SetNetDialTimeout(d time.Duration) NetOptions {
+	return func(o *netOptions) {
+		o.dialTimeout = d
+	}
+}
+
+// SetNetWriteTimeout sets the write deadline applied to every record.
+// Zero means writes never time out.
+func SetNetWriteTimeout(d time.Duration) NetOptions {
+	return func(o *netOptions) {
+		o.writeTimeout = d
+	}
+}
+
+// SetNetBackoff sets the delay before the first reconnection attempt and
+// the limit it doubles up to after each failed attempt.
+func SetNetBackoff(min, max time.Duration) NetOptions {
+	return func(o *netOptions) {
+		if min > 0 {
+			o.minBackoff = min
+		}
+		if max >= o.minBackoff {
+			o.maxBackoff = max
+		}
+	}
+}
+
+// SetNetBufferSize sets how many records are kept while disconnected.
+// When the buffer is full the oldest record is dropped. Zero disables
+// buffering, so records logged while disconnected fail with
+// ErrNetDisconnected, which suits FailoverHandler.
+func SetNetBufferSize(size int) NetOptions {
+	return func(o *netOptions) {
+		if size >= 0 {
+			o.bufferSize = size
+		}
+	}
+}
+
+// SetNetStateHook registers a function called whenever the connection
+// state changes. err is the reason for a disconnection, if any. The hook
+// runs with the handler locked, so it must not block or log through the
+// same handler.
+func SetNetStateHook(fn func(state ConnState, err error)) NetOptions {
+	return func(o *netOptions) {
+		o.stateHook = fn
+	}
+}
+
+// LoadTLSConfig builds a client TLS config from PEM files. certFile and
+// keyFile hold the client certificate, caFile the CAs used to verify the
+// server; either may be left empty.
+func LoadTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	config := &tls.Config{}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't load client certificate: %s", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't read CA file: %s", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
+
+// NetReconnect is a Handler which writes records over a network
+// connection that it re-establishes whenever it breaks. See
+// NetHandlerReconnect.
+type NetReconnect struct {
+	network, addr string
+	fmtr          Format
+	opts          netOptions
+
+	state   int32 // ConnState
+	dropped uint64
+
+	mu           sync.Mutex
+	conn         net.Conn
+	pending      [][]byte
+	reconnecting bool
+	closed       chan struct{}
+}
+
+// NetHandlerReconnect returns a handler which writes records to the
+// given address. Unlike NetHandler, a failed connection doesn't break it
+// for good: while it is down records are kept in a bounded buffer and a
+// background goroutine redials with exponential backoff, sending the
+// buffered records once connected again. network may be anything
+// net.Dial accepts, such as "tcp" or "unix"; use SetNetTLS for TLS.
+// Like StreamHandler, it evaluates Lazy values before formatting.
+//
+// The first connection is attempted before NetHandlerReconnect returns,
+// but failing to connect is not an error. Close flushes what it can and
+// closes the connection.
+func NetHandlerReconnect(network, addr string, fmtr Format, opts ...NetOptions) *NetReconnect {
+	o := _defaultNetOptions
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(&o)
+	}
+
+	h := &NetReconnect{
+		network: network,
+		addr:    addr,
+		fmtr:    fmtr,
+		opts:    o,
+		closed:  make(chan struct{}),
+	}
+
+	conn, err := h.dial()
+	h.mu.Lock()
+	if err == nil {
+		h.conn = conn
+		h.setState(NetConnected, nil)
+	} else {
+		h.disconnected(err)
+	}
+	h.mu.Unlock()
+	return h
+}
+
+// State reports whether the handler is currently connected.
+func (h *NetReconnect) State() ConnState {
+	return ConnState(atomic.LoadInt32(&h.state))
+}
+
+// Dropped returns the number of records discarded because the buffer
+// was full or the handler was closed before they could be sent.
+func (h *NetReconnect) Dropped() uint64 {
+	return atomic.LoadUint64(&h.dropped)
+}
+
+func (h *NetReconnect) Log(r *Record) error {
+	evaluateLazies(r)
+	buf := getBuffer()
+	defer putBuffer(buf)
+	formatBuffer(buf, h.fmtr, r)
+	b := buf.Bytes()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	select {
+	case <-h.closed:
+		return ErrHandlerClosed
+	default:
+	}
+
+	if h.conn != nil {
+		if err := h.writePending(); err == nil {
+			err = h.write(b)
+			if err == nil {
+				return nil
+			}
+			h.disconnected(err)
+		}
+	}
+
+	if h.opts.bufferSize == 0 {
+		return ErrNetDisconnected
+	}
+	// b belongs to the pooled buffer
+	h.buffer(append([]byte(nil), b...))
+	return nil
+}
+
+// Flush sends any buffered records if the handler is connected.
+func (h *NetReconnect) Flush() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.conn == nil {
+		return nil
+	}
+	return h.writePending()
+}
+
+func (h *NetReconnect) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	select {
+	case <-h.closed:
+		return nil
+	default:
+	}
+	close(h.closed)
+
+	var err error
+	if h.conn != nil {
+		err = h.writePending()
+		if h.conn != nil {
+			if errClose := h.conn.Close(); err == nil {
+				err = errClose
+			}
+			h.conn = nil
+		}
+	}
+	atomic.AddUint64(&h.dropped, uint64(len(h.pending)))
+	h.pending = nil
+	h.setState(NetClosed, nil)
+	return err
+}
+
+func (h *NetReconnect) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: h.opts.dialTimeout}
+	if h.opts.tlsConfig != nil {
+		return tls.DialWithDialer(dialer, h.network, h.addr, h.opts.tlsConfig)
+	}
+	return dialer.Dial(h.network, h.addr)
+}
+
+// write sends b over the current connection. h.mu must be held.
+func (h *NetReconnect) write(b []byte) error {
+	if h.opts.writeTimeout > 0 {
+		h.conn.SetWriteDeadline(time.Now().Add(h.opts.writeTimeout))
+	}
+	_, err := h.conn.Write(b)
+	return err
+}
+
+// writePending sends the buffered records, dropping the connection if
+// one fails. h.mu must be held.
+func (h *NetReconnect) writePending() error {
+	for len(h.pending) > 0 {
+		if err := h.write(h.pending[0]); err != nil {
+			h.disconnected(err)
+			return err
+		}
+		h.pending[0] = nil
+		h.pending = h.pending[1:]
+	}
+	h.pending = nil
+	return nil
+}
+
+// buffer keeps b until the connection is back, dropping the oldest
+// record if the buffer is full. h.mu must be held.
+func (h *NetReconnect) buffer(b []byte) {
+	if len(h.pending) >= h.opts.bufferSize {
+		atomic.AddUint64(&h.dropped, 1)
+		h.pending[0] = nil
+		h.pending = h.pending[1:]
+	}
+	h.pending = append(h.pending, b)
+}
+
+// disconnected drops the current connection and starts the reconnect
+// loop unless it is already running. h.mu must be held.
+func (h *NetReconnect) disconnected(err error) {
+	if h.conn != nil {
+		h.conn.Close()
+		h.conn = nil
+	}
+	h.setState(NetDisconnected, err)
+	if !h.reconnecting {
+		h.reconnecting = true
+		go h.reconnect()
+	}
+}
+
+func (h *NetReconnect) reconnect() {
+	backoff := h.opts.minBackoff
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-h.closed:
+			return
+		case <-timer.C:
+		}
+
+		conn, err := h.dial()
+		if err == nil {
+			h.mu.Lock()
+			select {
+			case <-h.closed:
+				h.mu.Unlock()
+				conn.Close()
+				return
+			default:
+			}
+			h.conn = conn
+			if err = h.writePending(); err == nil {
+				h.reconnecting = false
+				h.setState(NetConnected, nil)
+				h.mu.Unlock()
+				return
+			}
+			// writePending already dropped the connection
+			h.mu.Unlock()
+		}
+
+		if backoff *= 2; backoff > h.opts.maxBackoff {
+			backoff = h.opts.maxBackoff
+		}
+		timer.Reset(backoff)
+	}
+}
+
+func (h *NetReconnect) setState(state ConnState, err error) {
+	old := ConnState(atomic.SwapInt32(&h.state, int32(state)))
+	if old != state && h.opts.stateHook != nil {
+		h.opts.stateHook(state, err)
+	}
+}
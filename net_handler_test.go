@@ -0,0 +1,203 @@
+package log
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lineServer accepts connections and sends every line it reads to lines.
+type lineServer struct {
+	ln    net.Listener
+	lines chan string
+	conns chan net.Conn
+}
+
+func newLineServer(t *testing.T, ln net.Listener) *lineServer {
+	s := &lineServer{ln: ln, lines: make(chan string, 100), conns: make(chan net.Conn, 10)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.conns <- conn
+			go func() {
+				sc := bufio.NewScanner(conn)
+				for sc.Scan() {
+					s.lines <- sc.Text()
+				}
+			}()
+		}
+	}()
+	return s
+}
+
+// kill stops accepting and drops every open connection.
+func (s *lineServer) kill() {
+	s.ln.Close()
+	for {
+		select {
+		case c := <-s.conns:
+			c.Close()
+		default:
+			return
+		}
+	}
+}
+
+func (s *lineServer) expect(t *testing.T, msg string) {
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case line := <-s.lines:
+			if strings.Contains(line, msg) {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("never received %q", msg)
+		}
+	}
+}
+
+func msgFormat() Format {
+	return FormatFunc(func(r *Record) []byte {
+		return []byte(r.Msg + "\n")
+	})
+}
+
+func TestNetHandlerReconnect(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	srv := newLineServer(t, ln)
+
+	states := make(chan ConnState, 10)
+	h := NetHandlerReconnect("tcp", addr, msgFormat(),
+		SetNetBackoff(10*time.Millisecond, 50*time.Millisecond),
+		SetNetStateHook(func(state ConnState, err error) { states <- state }))
+	defer h.Close()
+
+	if h.State() != NetConnected {
+		t.Fatalf("got state %v, expected connected", h.State())
+	}
+	h.Log(&Record{Msg: "first"})
+	srv.expect(t, "first")
+
+	srv.kill()
+	// a write to a freshly reset TCP connection may still succeed, so keep
+	// logging until the handler notices
+	for i := 0; h.State() == NetConnected; i++ {
+		if i > 500 {
+			t.Fatalf("handler never noticed the connection was gone")
+		}
+		h.Log(&Record{Msg: "lost"})
+		time.Sleep(time.Millisecond)
+	}
+	h.Log(&Record{Msg: "buffered"})
+
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("can't listen on %s again: %v", addr, err)
+	}
+	srv = newLineServer(t, ln)
+	defer srv.kill()
+
+	srv.expect(t, "buffered")
+	h.Log(&Record{Msg: "after"})
+	srv.expect(t, "after")
+
+	if got := <-states; got != NetConnected {
+		t.Fatalf("got state change %v, expected connected", got)
+	}
+	if got := <-states; got != NetDisconnected {
+		t.Fatalf("got state change %v, expected disconnected", got)
+	}
+	if got := <-states; got != NetConnected {
+		t.Fatalf("got state change %v, expected connected", got)
+	}
+}
+
+func TestNetHandlerReconnectNoBuffer(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	h := NetHandlerReconnect("tcp", addr, msgFormat(), SetNetBufferSize(0), SetNetBackoff(time.Hour, time.Hour))
+	if h.State() != NetDisconnected {
+		t.Fatalf("got state %v, expected disconnected", h.State())
+	}
+	if err := h.Log(&Record{Msg: "x"}); err != ErrNetDisconnected {
+		t.Fatalf("got %v, expected %v", err, ErrNetDisconnected)
+	}
+	h.Close()
+	if err := h.Log(&Record{Msg: "x"}); err != ErrHandlerClosed {
+		t.Fatalf("got %v, expected %v", err, ErrHandlerClosed)
+	}
+}
+
+func TestNetHandlerReconnectTLS(t *testing.T) {
+	t.Parallel()
+
+	cert, pool := testCertificate(t)
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newLineServer(t, ln)
+	defer srv.kill()
+
+	h := NetHandlerReconnect("tcp", ln.Addr().String(), msgFormat(),
+		SetNetTLS(&tls.Config{RootCAs: pool, ServerName: "localhost"}))
+	defer h.Close()
+
+	h.Log(&Record{Msg: "secure"})
+	srv.expect(t, "secure")
+}
+
+func testCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
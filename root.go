@@ -5,6 +5,7 @@ import (
 	"github.com/inconshreveable/log15/term"
 	"github.com/mattn/go-colorable"
 	"os"
+	"time"
 )
 
 var (
@@ -22,7 +23,7 @@ func init() {
 		StderrHandler = StreamHandler(colorable.NewColorableStderr(), TerminalFormat())
 	}
 
-	root = &logger{[]interface{}{}, new(swapHandler), LvlTrace}
+	root = &logger{KeyValues: []interface{}{}, handler: new(swapHandler), level: LvlTrace}
 	root.SetHandler(StdoutHandler)
 }
 
@@ -45,6 +46,41 @@ func GetLogLevel() Level {
 	return root.GetOutLevel()
 }
 
+// SetKeyNames sets the root logger's key names, which every logger that
+// doesn't set its own uses. See Logger.SetKeyNames.
+func SetKeyNames(names RecordKeyNames) {
+	root.SetKeyNames(names)
+}
+
+// SetClock sets the root logger's clock, which every logger that doesn't
+// set its own uses. See Logger.SetClock.
+func SetClock(now func() time.Time) {
+	root.SetClock(now)
+}
+
+// Shutdown flushes and then closes the root logger's handler tree,
+// releasing any files or connections held by its handlers. Call it
+// once, right before the program exits. If ctx is done before the
+// handlers finish, Shutdown returns ctx.Err() and leaves them to
+// finish in the background.
+func Shutdown(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		err := FlushHandler(root.handler)
+		if errClose := CloseHandler(root.handler); err == nil && errClose != nil {
+			err = errClose
+		}
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // The following functions bypass the exported logger methods (logger.Debug,
 // etc.) to keep the call depth the same for all paths to logger.write so
 // runtime.Caller(2) always refers to the call site in client code.
@@ -59,8 +95,33 @@ func LogContext(ctx context.Context, msg string, keyValues ...interface{}) {
 	root.writeContext(ctx, msg, LvlTrace, keyValues)
 }
 
+// LogLevel is a convenient alias for Root().LogLevel
+func LogLevel(level Level, msg string, keyValues ...interface{}) {
+	root.write(msg, level, keyValues)
+	if level == LvlFatal {
+		FlushAndExit(root.handler, 1)
+	}
+}
+
+// LogLevelContext is a convenient alias for Root().LogLevelContext
+func LogLevelContext(ctx context.Context, level Level, msg string, keyValues ...interface{}) {
+	root.writeContext(ctx, msg, level, keyValues)
+	if level == LvlFatal {
+		FlushAndExit(root.handler, 1)
+	}
+}
+
+// LogFields is a convenient alias for Root().LogFields. It is the
+// allocation-free way to skip disabled levels.
+func LogFields(ctx context.Context, level Level, msg string, fields ...Field) {
+	root.writeFields(ctx, msg, level, fields)
+	if level == LvlFatal {
+		FlushAndExit(root.handler, 1)
+	}
+}
+
 func IsDebugEnable() bool {
-	return root.level >= LvlDebug
+	return root.maxLevel(1).Compare(LvlDebug) >= 0
 }
 
 // Debug is a convenient alias for Root().Debug
@@ -74,7 +135,7 @@ func DebugContext(ctx context.Context, msg string, keyValues ...interface{}) {
 }
 
 func IsInfoEnable() bool {
-	return root.level >= LvlInfo
+	return root.maxLevel(1).Compare(LvlInfo) >= 0
 }
 
 // Info is a convenient alias for Root().Info
@@ -88,7 +149,7 @@ func InfoContext(ctx context.Context, msg string, keyValues ...interface{}) {
 }
 
 func IsWarnEnable() bool {
-	return root.level >= LvlWarn
+	return root.maxLevel(1).Compare(LvlWarn) >= 0
 }
 
 // Warn is a convenient alias for Root().Warn
@@ -102,7 +163,7 @@ func WarnContext(ctx context.Context, msg string, keyValues ...interface{}) {
 }
 
 func IsErrorEnable() bool {
-	return root.level >= LvlError
+	return root.maxLevel(1).Compare(LvlError) >= 0
 }
 
 // Error is a convenient alias for Root().Error
@@ -116,16 +177,17 @@ func ErrorContext(ctx context.Context, msg string, keyValues ...interface{}) {
 }
 
 func IsFatalEnable() bool {
-	return root.level >= LvlFatal
+	return root.maxLevel(1).Compare(LvlFatal) >= 0
 }
 
 // Fatal is a convenient alias for Root().Fatal
 func Fatal(msg string, keyValues ...interface{}) {
 	root.write(msg, LvlFatal, keyValues)
-	os.Exit(1)
+	FlushAndExit(root.handler, 1)
 }
 
 // Fatal is a convenient alias for Root().Fatal
 func FatalContext(ctx context.Context, msg string, keyValues ...interface{}) {
 	root.writeContext(ctx, msg, LvlFatal, keyValues)
+	FlushAndExit(root.handler, 1)
 }
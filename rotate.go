@@ -8,15 +8,36 @@ import (
 
 // rotateOptions storage rotate file parameters
 type rotateOptions struct {
-	maxSize       int                // 单个文件大小
-	maxSaveDay    int                // 文件最多存储天数
-	maxBackup     int                // 最多备份数量
-	doesCompress  bool               // 切割后文件是否压缩
-	doesDayRotate bool               // 是否日切
-	output        *lumberjack.Logger // 文件指针
+	maxSize       int                    // 单个文件大小
+	maxSaveDay    int                    // 文件最多存储天数
+	maxBackup     int                    // 最多备份数量
+	doesCompress  bool                   // 切割后文件是否压缩
+	doesDayRotate bool                   // 是否日切
+	output        *lumberjack.Logger     // 文件指针
+	interval      string                 // 按周期切割: minute, hour, day, week 或 cron 表达式
+	timeZone      string                 // 周期及备份名所用时区
+	backupNaming  string                 // 备份命名方式: time 或 number
+	backupLayout  string                 // 备份名中的时间格式
+	backupDir     string                 // 备份所在目录
+	backupDateDir string                 // 备份按日期分目录的时间格式
+	namer         lumberjack.Namer       // 自定义备份命名, 优先于以上选项
+	codecName     string                 // 压缩方式: gzip, zstd 或 none
+	codecLevel    int                    // 压缩级别, 0 表示默认
+	codecThreads  int                    // 压缩单个文件的并发数
+	codec         lumberjack.Codec       // 自定义压缩方式, 优先于以上选项
+	maxTotalSize  int                    // 日志及备份的总大小上限
+	minFreeSpace  int                    // 文件系统最少剩余空间
+	diskFull      string                 // 空间不足时的写入策略: write, stop 或 sample
+	diskFullRate  int                    // sample 策略下每多少次写入一次
+	onEvent       func(lumberjack.Event) // 切割, 压缩, 删除及失败事件的回调
 }
 
-var _defaultRotateOption = &rotateOptions{100, 7, 50, false, true, nil}
+var _defaultRotateOption = &rotateOptions{
+	maxSize:       100,
+	maxSaveDay:    7,
+	maxBackup:     50,
+	doesDayRotate: true,
+}
 
 func GetDefaultRotateOption() *rotateOptions {
 	return _defaultRotateOption
@@ -80,8 +101,213 @@ func (opts *rotateOptions) SetOutput(output *lumberjack.Logger) {
 	opts.output = output
 }
 
+// SetRotateInterval rotates the file whenever a new period starts, by a
+// timer rather than on the next write: interval is minute, hour, day,
+// week or a cron expression such as "0 */6 * * *", see
+// lumberjack.ParseSchedule. Backups are named after their period, as in
+// app.2026-10-17-13.log.
+func SetRotateInterval(interval string) RotateOptions {
+	return func(o *rotateOptions) {
+		o.SetRotateInterval(interval)
+	}
+}
+
+func (opts *rotateOptions) SetRotateInterval(interval string) {
+	opts.interval = interval
+}
+
+// SetRotateTimeZone sets the IANA time zone, such as "Asia/Shanghai",
+// periods start in and backups are named in; the local one by default.
+func SetRotateTimeZone(tz string) RotateOptions {
+	return func(o *rotateOptions) {
+		o.SetRotateTimeZone(tz)
+	}
+}
+
+func (opts *rotateOptions) SetRotateTimeZone(tz string) {
+	opts.timeZone = tz
+}
+
+// SetBackupNaming sets how backups are named: lumberjack.NamingTime, the
+// default, after the time they were rotated as in
+// app-2026-10-17T13-20-00.000.log, or lumberjack.NamingNumber,
+// logrotate-style as in app.log.1, app.log.2, the newest first.
+func SetBackupNaming(naming string) RotateOptions {
+	return func(o *rotateOptions) {
+		o.SetBackupNaming(naming)
+	}
+}
+
+func (opts *rotateOptions) SetBackupNaming(naming string) {
+	opts.backupNaming = naming
+}
+
+// SetBackupLayout sets the time layout of the names of backups, such as
+// "20060102-150405".
+func SetBackupLayout(layout string) RotateOptions {
+	return func(o *rotateOptions) {
+		o.SetBackupLayout(layout)
+	}
+}
+
+func (opts *rotateOptions) SetBackupLayout(layout string) {
+	opts.backupLayout = layout
+}
+
+// SetBackupDir moves backups to dir, relative to the log file's directory
+// unless absolute, such as "archive".
+func SetBackupDir(dir string) RotateOptions {
+	return func(o *rotateOptions) {
+		o.SetBackupDir(dir)
+	}
+}
+
+func (opts *rotateOptions) SetBackupDir(dir string) {
+	opts.backupDir = dir
+}
+
+// SetBackupDateDir partitions backups into directories named with layout,
+// such as "2006/01/02", under the backup directory.
+func SetBackupDateDir(layout string) RotateOptions {
+	return func(o *rotateOptions) {
+		o.SetBackupDateDir(layout)
+	}
+}
+
+func (opts *rotateOptions) SetBackupDateDir(layout string) {
+	opts.backupDateDir = layout
+}
+
+// SetBackupNamer names backups with a lumberjack.Namer of one's own, in
+// place of the backup options above.
+func SetBackupNamer(namer lumberjack.Namer) RotateOptions {
+	return func(o *rotateOptions) {
+		o.SetBackupNamer(namer)
+	}
+}
+
+func (opts *rotateOptions) SetBackupNamer(namer lumberjack.Namer) {
+	opts.namer = namer
+}
+
+// SetCompressCodec sets the codec backups are compressed with when
+// compression is on: lumberjack.CodecGzip, the default,
+// lumberjack.CodecZstd or lumberjack.CodecNone, at level, the codec's
+// default if 0.
+func SetCompressCodec(name string, level int) RotateOptions {
+	return func(o *rotateOptions) {
+		o.SetCompressCodec(name, level)
+	}
+}
+
+func (opts *rotateOptions) SetCompressCodec(name string, level int) {
+	opts.codecName = name
+	opts.codecLevel = level
+}
+
+// SetCompressConcurrency compresses each backup with n goroutines, so
+// that large backups don't keep the next ones waiting for minutes.
+func SetCompressConcurrency(n int) RotateOptions {
+	return func(o *rotateOptions) {
+		o.SetCompressConcurrency(n)
+	}
+}
+
+func (opts *rotateOptions) SetCompressConcurrency(n int) {
+	opts.codecThreads = n
+}
+
+// SetCodec compresses backups with a lumberjack.Codec of one's own, in
+// place of the codec options above.
+func SetCodec(codec lumberjack.Codec) RotateOptions {
+	return func(o *rotateOptions) {
+		o.SetCodec(codec)
+	}
+}
+
+func (opts *rotateOptions) SetCodec(codec lumberjack.Codec) {
+	opts.codec = codec
+}
+
+// SetMaxTotalSize caps the log file and its backups at max megabytes
+// together, deleting the oldest backups to stay within it.
+func SetMaxTotalSize(max int) RotateOptions {
+	return func(o *rotateOptions) {
+		o.SetMaxTotalSize(max)
+	}
+}
+
+func (opts *rotateOptions) SetMaxTotalSize(max int) {
+	opts.maxTotalSize = max
+}
+
+// SetMinFreeSpace leaves min megabytes free on the file system of the log
+// file, deleting the oldest backups if there is less.
+func SetMinFreeSpace(min int) RotateOptions {
+	return func(o *rotateOptions) {
+		o.SetMinFreeSpace(min)
+	}
+}
+
+func (opts *rotateOptions) SetMinFreeSpace(min int) {
+	opts.minFreeSpace = min
+}
+
+// SetDiskFullPolicy sets what is done with writes while less than the
+// minimum free space is left: lumberjack.DiskFullWrite, the default,
+// writes them, lumberjack.DiskFullStop drops them and
+// lumberjack.DiskFullSample writes one in rate of them.
+func SetDiskFullPolicy(policy string, rate int) RotateOptions {
+	return func(o *rotateOptions) {
+		o.SetDiskFullPolicy(policy, rate)
+	}
+}
+
+func (opts *rotateOptions) SetDiskFullPolicy(policy string, rate int) {
+	opts.diskFull = policy
+	opts.diskFullRate = rate
+}
+
+// SetRotateEventHook has fn told when the file is rotated and when its
+// backups are compressed or deleted, and of the errors doing so or
+// writing; see lumberjack.Event.
+func SetRotateEventHook(fn func(lumberjack.Event)) RotateOptions {
+	return func(o *rotateOptions) {
+		o.SetRotateEventHook(fn)
+	}
+}
+
+func (opts *rotateOptions) SetRotateEventHook(fn func(lumberjack.Event)) {
+	opts.onEvent = fn
+}
+
+// newRotateOptions applies the default options and then opts on top of
+// lumberjack's defaults.
 func newRotateOptions(opts []RotateOptions) *rotateOptions {
-	var o rotateOptions
+	d := lumberjack.DefaultRotateOption()
+	o := rotateOptions{
+		maxSize:       d.MaxSize,
+		maxSaveDay:    d.MaxAge,
+		maxBackup:     d.MaxBackups,
+		doesCompress:  d.Compress,
+		doesDayRotate: d.DayRotate,
+		interval:      d.Interval,
+		timeZone:      d.TimeZone,
+		backupNaming:  d.BackupNaming,
+		backupLayout:  d.BackupLayout,
+		backupDir:     d.BackupDir,
+		backupDateDir: d.BackupDateDir,
+		namer:         d.Namer,
+		codecName:     d.CompressCodec,
+		codecLevel:    d.CompressLevel,
+		codecThreads:  d.CompressConcurrency,
+		codec:         d.Codec,
+		maxTotalSize:  d.MaxTotalSize,
+		minFreeSpace:  d.MinFreeSpace,
+		diskFull:      d.DiskFull,
+		diskFullRate:  d.DiskFullRate,
+		onEvent:       d.OnEvent,
+	}
 	for _, opt := range getDefaultRotateOptions() {
 		if opt == nil {
 			continue
@@ -97,6 +323,33 @@ func newRotateOptions(opts []RotateOptions) *rotateOptions {
 	return &o
 }
 
+// rotateOption converts o to lumberjack's options.
+func (opts *rotateOptions) rotateOption() lumberjack.RotateOption {
+	ro := lumberjack.DefaultRotateOption()
+	ro.MaxSize = opts.maxSize
+	ro.MaxAge = opts.maxSaveDay
+	ro.MaxBackups = opts.maxBackup
+	ro.Compress = opts.doesCompress
+	ro.DayRotate = opts.doesDayRotate
+	ro.Interval = opts.interval
+	ro.TimeZone = opts.timeZone
+	ro.BackupNaming = opts.backupNaming
+	ro.BackupLayout = opts.backupLayout
+	ro.BackupDir = opts.backupDir
+	ro.BackupDateDir = opts.backupDateDir
+	ro.Namer = opts.namer
+	ro.CompressCodec = opts.codecName
+	ro.CompressLevel = opts.codecLevel
+	ro.CompressConcurrency = opts.codecThreads
+	ro.Codec = opts.codec
+	ro.MaxTotalSize = opts.maxTotalSize
+	ro.MinFreeSpace = opts.minFreeSpace
+	ro.DiskFull = opts.diskFull
+	ro.DiskFullRate = opts.diskFullRate
+	ro.OnEvent = opts.onEvent
+	return ro
+}
+
 var _defaultOptionsPtr unsafe.Pointer // *[]RotateOption
 
 func SetDefaultRotateOptions(opts []RotateOptions) {
@@ -0,0 +1,423 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Facility is a syslog facility code.
+type Facility int
+
+const (
+	FacilityKern Facility = iota
+	FacilityUser
+	FacilityMail
+	FacilityDaemon
+	FacilityAuth
+	FacilitySyslog
+	FacilityLPR
+	FacilityNews
+	FacilityUUCP
+	FacilityCron
+	FacilityAuthPriv
+	FacilityFTP
+)
+
+const (
+	FacilityLocal0 Facility = iota + 16
+	FacilityLocal1
+	FacilityLocal2
+	FacilityLocal3
+	FacilityLocal4
+	FacilityLocal5
+	FacilityLocal6
+	FacilityLocal7
+)
+
+// SyslogProtocol selects the syslog message layout.
+type SyslogProtocol int
+
+const (
+	// SyslogRFC5424 is the structured syslog protocol. Key/value pairs
+	// are sent as structured data.
+	SyslogRFC5424 SyslogProtocol = iota
+	// SyslogRFC3164 is the traditional BSD syslog protocol. Key/value
+	// pairs are appended to the message in logfmt style.
+	SyslogRFC3164
+)
+
+const (
+	syslogTimeFormat5424 = "2006-01-02T15:04:05.000000Z07:00"
+	syslogTimeFormat3164 = "Jan _2 15:04:05"
+	syslogNil            = "-"
+)
+
+// syslog severities
+const (
+	sevEmerg = iota
+	sevAlert
+	sevCrit
+	sevErr
+	sevWarning
+	sevNotice
+	sevInfo
+	sevDebug
+)
+
+// syslogOptions storage syslog format parameters
+type syslogOptions struct {
+	protocol SyslogProtocol // 报文格式
+	facility Facility       // 设施
+	tag      string         // APP-NAME / TAG
+	hostname string         // HOSTNAME
+	msgID    string         // RFC5424 MSGID
+	sdID     string         // RFC5424 SD-ID
+	local    bool           // 本地 socket，RFC3164 省略 HOSTNAME
+	netOpts  []NetOptions   // 底层连接参数
+}
+
+func newSyslogOptions(opts []SyslogOptions) syslogOptions {
+	hostname, _ := os.Hostname()
+	o := syslogOptions{
+		protocol: SyslogRFC5424,
+		facility: FacilityUser,
+		tag:      filepath.Base(os.Args[0]),
+		hostname: hostname,
+		sdID:     "fields@32473",
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(&o)
+	}
+	return o
+}
+
+type SyslogOptions func(*syslogOptions)
+
+// SetSyslogProtocol selects RFC 5424 (the default) or RFC 3164 messages.
+func SetSyslogProtocol(protocol SyslogProtocol) SyslogOptions {
+	return func(o *syslogOptions) {
+		o.protocol = protocol
+	}
+}
+
+// SetSyslogFacility sets the facility, FacilityUser by default.
+func SetSyslogFacility(facility Facility) SyslogOptions {
+	return func(o *syslogOptions) {
+		o.facility = facility
+	}
+}
+
+// SetSyslogTag sets the RFC 5424 APP-NAME or RFC 3164 TAG. It defaults
+// to the program name.
+func SetSyslogTag(tag string) SyslogOptions {
+	return func(o *syslogOptions) {
+		o.tag = tag
+	}
+}
+
+// SetSyslogHostname overrides the hostname reported in each message.
+func SetSyslogHostname(hostname string) SyslogOptions {
+	return func(o *syslogOptions) {
+		o.hostname = hostname
+	}
+}
+
+// SetSyslogMsgID sets the RFC 5424 MSGID field.
+func SetSyslogMsgID(msgID string) SyslogOptions {
+	return func(o *syslogOptions) {
+		o.msgID = msgID
+	}
+}
+
+// SetSyslogStructuredDataID sets the RFC 5424 SD-ID that holds the
+// record's key/value pairs. It defaults to "fields@32473", an ID under
+// the IANA example enterprise number; use your own enterprise number in
+// production.
+func SetSyslogStructuredDataID(id string) SyslogOptions {
+	return func(o *syslogOptions) {
+		o.sdID = id
+	}
+}
+
+// SetSyslogNetOptions passes options to the underlying NetHandlerReconnect.
+func SetSyslogNetOptions(opts ...NetOptions) SyslogOptions {
+	return func(o *syslogOptions) {
+		o.netOpts = append(o.netOpts, opts...)
+	}
+}
+
+// syslogSeverity maps a Level to a syslog severity.
+func syslogSeverity(l Level) int {
+	switch {
+	case l.Compare(LvlFatal) <= 0:
+		return sevCrit
+	case l.Compare(LvlError) <= 0:
+		return sevErr
+	case l.Compare(LvlWarn) <= 0:
+		return sevWarning
+	case l.Compare(LvlInfo) < 0:
+		return sevNotice
+	case l.Compare(LvlInfo) <= 0:
+		return sevInfo
+	default:
+		return sevDebug
+	}
+}
+
+// SyslogFormat formats records as syslog messages without any transport
+// framing. Level maps to severity: LvlFatal is crit, LvlError err,
+// LvlWarn warning, LvlInfo info and LvlDebug and LvlTrace debug. Custom
+// levels take the severity of the next built-in level, except that those
+// between LvlWarn and LvlInfo are notice.
+//
+// RFC 5424 example:
+//
+//     <14>1 2026-10-17T13:04:05.000000+08:00 host app 4242 - [fields@32473 location="main.go:12" user="bob"] page accessed
+//
+// RFC 3164 example:
+//
+//     <14>Oct 17 13:04:05 host app[4242]: page accessed location=main.go:12 user=bob
+//
+func SyslogFormat(opts ...SyslogOptions) Format {
+	o := newSyslogOptions(opts)
+	return syslogFormat(o)
+}
+
+func syslogFormat(o syslogOptions) Format {
+	pid := strconv.Itoa(os.Getpid())
+	if o.protocol == SyslogRFC3164 {
+		return BufferFormatFunc(func(b *bytes.Buffer, r *Record) {
+			syslog3164(b, r, o, pid)
+		})
+	}
+
+	hostname := syslogHeaderField(o.hostname, 255)
+	tag := syslogHeaderField(o.tag, 48)
+	msgID := syslogHeaderField(o.msgID, 32)
+	sdID := syslogSDName(o.sdID)
+	return BufferFormatFunc(func(b *bytes.Buffer, r *Record) {
+		b.WriteByte('<')
+		b.WriteString(strconv.Itoa(int(o.facility)*8 + syslogSeverity(r.Level)))
+		b.WriteString(">1 ")
+		b.WriteString(r.Time.Format(syslogTimeFormat5424))
+		b.WriteByte(' ')
+		b.WriteString(hostname)
+		b.WriteByte(' ')
+		b.WriteString(tag)
+		b.WriteByte(' ')
+		b.WriteString(pid)
+		b.WriteByte(' ')
+		b.WriteString(msgID)
+		b.WriteByte(' ')
+
+		b.WriteByte('[')
+		b.WriteString(sdID)
+		writeSyslogParam(b, r.KeyNames.Call, syslogCaller(r))
+		for i := 0; i+1 < len(r.KeyValues); i += 2 {
+			k, ok := r.KeyValues[i].(string)
+			if !ok {
+				writeSyslogParam(b, errorKey, formatShared(r.KeyValues[i]))
+				continue
+			}
+			writeSyslogParam(b, k, r.KeyValues[i+1])
+		}
+		for _, f := range r.Fields {
+			writeSyslogParam(b, f.Key, f.Value())
+		}
+		b.WriteByte(']')
+
+		if r.Msg != "" {
+			b.WriteByte(' ')
+			b.WriteString(r.Msg)
+		}
+	})
+}
+
+func syslog3164(b *bytes.Buffer, r *Record, o syslogOptions, pid string) {
+	b.WriteByte('<')
+	b.WriteString(strconv.Itoa(int(o.facility)*8 + syslogSeverity(r.Level)))
+	b.WriteByte('>')
+	b.WriteString(r.Time.Format(syslogTimeFormat3164))
+	b.WriteByte(' ')
+	if !o.local && o.hostname != "" {
+		b.WriteString(o.hostname)
+		b.WriteByte(' ')
+	}
+	b.WriteString(o.tag)
+	b.WriteByte('[')
+	b.WriteString(pid)
+	b.WriteString("]: ")
+	b.WriteString(r.Msg)
+
+	b.WriteByte(' ')
+	appendLogfmtKey(b, r.KeyNames.Call)
+	b.WriteByte('=')
+	b.WriteString(formatLogfmtValue(syslogCaller(r)))
+	for i := 0; i+1 < len(r.KeyValues); i += 2 {
+		k, ok := r.KeyValues[i].(string)
+		v := formatLogfmtValue(r.KeyValues[i+1])
+		if !ok {
+			k, v = errorKey, formatLogfmtValue(r.KeyValues[i])
+		}
+		b.WriteByte(' ')
+		appendLogfmtKey(b, k)
+		b.WriteByte('=')
+		b.WriteString(v)
+	}
+	for _, f := range r.Fields {
+		b.WriteByte(' ')
+		appendLogfmtKey(b, f.Key)
+		b.WriteByte('=')
+		f.appendLogfmtValue(b)
+	}
+}
+
+func syslogCaller(r *Record) string {
+	if r.CustomCaller != "" {
+		return r.CustomCaller
+	}
+	return r.Call.String()
+}
+
+// syslogHeaderField makes s a valid RFC 5424 header field: printable
+// US-ASCII without spaces, at most max characters, or "-" if empty.
+func syslogHeaderField(s string, max int) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, s)
+	if len(s) > max {
+		s = s[:max]
+	}
+	if s == "" {
+		return syslogNil
+	}
+	return s
+}
+
+// syslogSDName makes s a valid SD-NAME: a header field without
+// '=', ']', '"' or space, at most 32 characters.
+func syslogSDName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, s)
+	if len(s) > 32 {
+		s = s[:32]
+	}
+	if s == "" {
+		return "_"
+	}
+	return s
+}
+
+func writeSyslogParam(b *bytes.Buffer, key string, value interface{}) {
+	b.WriteByte(' ')
+	b.WriteString(syslogSDName(key))
+	b.WriteString(`="`)
+	var s string
+	switch v := formatShared(value).(type) {
+	case string:
+		s = v
+	case nil:
+		s = "nil"
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		s = formatLogfmtValue(v)
+	default:
+		s = fmt.Sprintf("%+v", v)
+	}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\\', ']':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+}
+
+// octetCountingFormat frames each message as "LEN SP MSG" (RFC 6587),
+// which is what syslog servers expect over stream transports.
+func octetCountingFormat(fmtr Format) Format {
+	return BufferFormatFunc(func(b *bytes.Buffer, r *Record) {
+		msg := getBuffer()
+		formatBuffer(msg, fmtr, r)
+		var scratch [20]byte
+		b.Write(strconv.AppendInt(scratch[:0], int64(msg.Len()), 10))
+		b.WriteByte(' ')
+		b.Write(msg.Bytes())
+		putBuffer(msg)
+	})
+}
+
+// newlineFormat terminates each message with a newline, which is how
+// local syslog daemons delimit messages on stream sockets.
+func newlineFormat(fmtr Format) Format {
+	return BufferFormatFunc(func(b *bytes.Buffer, r *Record) {
+		start := b.Len()
+		formatBuffer(b, fmtr, r)
+		if b.Len() == start || b.Bytes()[b.Len()-1] != '\n' {
+			b.WriteByte('\n')
+		}
+	})
+}
+
+func isStreamNetwork(network string) bool {
+	switch network {
+	case "udp", "udp4", "udp6", "unixgram":
+		return false
+	}
+	return true
+}
+
+// SyslogNetHandler returns a handler which sends syslog messages to a
+// remote syslog server. network is "udp", "tcp" or "unix"; stream
+// transports use octet-counting framing. The connection is managed by
+// NetHandlerReconnect, so it survives server restarts.
+func SyslogNetHandler(network, addr string, opts ...SyslogOptions) Handler {
+	o := newSyslogOptions(opts)
+	fmtr := syslogFormat(o)
+	if isStreamNetwork(network) {
+		fmtr = octetCountingFormat(fmtr)
+	}
+	return NetHandlerReconnect(network, addr, fmtr, o.netOpts...)
+}
+
+var syslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// SyslogHandler returns a handler which sends syslog messages to the
+// local syslog daemon through its unix socket.
+func SyslogHandler(opts ...SyslogOptions) (Handler, error) {
+	o := newSyslogOptions(opts)
+	o.local = true
+	fmtr := syslogFormat(o)
+
+	for _, network := range []string{"unixgram", "unix"} {
+		for _, path := range syslogSockets {
+			f := fmtr
+			if isStreamNetwork(network) {
+				f = newlineFormat(fmtr)
+			}
+			h := NetHandlerReconnect(network, path, f, o.netOpts...)
+			if h.State() == NetConnected {
+				return h, nil
+			}
+			h.Close()
+		}
+	}
+	return nil, errors.New("log: unix syslog delivery error")
+}
@@ -0,0 +1,134 @@
+package log
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-stack/stack"
+)
+
+func syslogTestRecord() *Record {
+	return &Record{
+		Time:         time.Date(2026, 10, 17, 13, 4, 5, 0, time.UTC),
+		Level:        LvlWarn,
+		Msg:          "disk almost full",
+		KeyValues:    []interface{}{"path", "/var", "used", 0.95, "note", `a "quoted" [x]`},
+		CustomCaller: "main.go:12",
+		KeyNames:     defaultRecordKeyNames,
+	}
+}
+
+func TestSyslogFormatRFC5424(t *testing.T) {
+	t.Parallel()
+
+	f := SyslogFormat(SetSyslogFacility(FacilityLocal0), SetSyslogTag("app"), SetSyslogHostname("host"))
+	got := string(f.Format(syslogTestRecord()))
+	pid := strconv.Itoa(os.Getpid())
+	exp := `<132>1 2026-10-17T13:04:05.000000Z host app ` + pid + ` - [fields@32473 location="main.go:12" path="/var" used="0.950" note="a \"quoted\" [x\]"] disk almost full`
+	if got != exp {
+		t.Fatalf("got  %s\nexpected %s", got, exp)
+	}
+}
+
+func TestSyslogFormatRFC3164(t *testing.T) {
+	t.Parallel()
+
+	f := SyslogFormat(SetSyslogProtocol(SyslogRFC3164), SetSyslogTag("app"), SetSyslogHostname("host"))
+	r := syslogTestRecord()
+	r.Level = LvlFatal
+	r.KeyValues = []interface{}{"path", "/var"}
+	got := string(f.Format(r))
+	exp := `<10>Oct 17 13:04:05 host app[` + strconv.Itoa(os.Getpid()) + `]: disk almost full location=main.go:12 path=/var`
+	if got != exp {
+		t.Fatalf("got  %s\nexpected %s", got, exp)
+	}
+}
+
+func TestSyslogSeverity(t *testing.T) {
+	t.Parallel()
+
+	for lvl, sev := range map[Level]int{LvlFatal: 2, LvlError: 3, LvlWarn: 4, LvlInfo: 6, LvlDebug: 7, LvlTrace: 7} {
+		if got := syslogSeverity(lvl); got != sev {
+			t.Errorf("%v: got severity %d, expected %d", lvl, got, sev)
+		}
+	}
+}
+
+func TestSyslogNetHandlerOctetCounting(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	msgs := make(chan string, 2)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		rd := bufio.NewReader(conn)
+		for {
+			n, err := rd.ReadString(' ')
+			if err != nil {
+				return
+			}
+			size, _ := strconv.Atoi(n[:len(n)-1])
+			buf := make([]byte, size)
+			if _, err := io.ReadFull(rd, buf); err != nil {
+				return
+			}
+			msgs <- string(buf)
+		}
+	}()
+
+	h := SyslogNetHandler("tcp", ln.Addr().String(), SetSyslogTag("app"), SetSyslogHostname("host"))
+	defer CloseHandler(h)
+	l := New()
+	l.SetHandler(h)
+	l.Info("first message")
+	l.Error("second\nmessage", "k", "v")
+
+	for _, suffix := range []string{"] first message", "] second\nmessage"} {
+		select {
+		case m := <-msgs:
+			if len(m) < len(suffix) || m[len(m)-len(suffix):] != suffix {
+				t.Fatalf("got message %q, expected it to end with %q", m, suffix)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("never received %q", suffix)
+		}
+	}
+}
+
+func TestSyslogNetHandlerUDP(t *testing.T) {
+	t.Parallel()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	h := SyslogNetHandler("udp", pc.LocalAddr().String(), SetSyslogProtocol(SyslogRFC3164), SetSyslogTag("app"))
+	defer CloseHandler(h)
+	h.Log(&Record{Time: time.Now(), Level: LvlInfo, Msg: "datagram", Call: stack.Caller(0), KeyNames: defaultRecordKeyNames})
+
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 1024)
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf[0] != '<' || buf[n-1] == '\n' {
+		t.Fatalf("expected an unframed message, got %q", buf[:n])
+	}
+}
@@ -0,0 +1,97 @@
+package log
+
+import (
+	"strconv"
+	"time"
+)
+
+// Time layouts which SetTimeFormat accepts besides those of the time
+// package. Epoch times are written as numbers.
+const (
+	TimeEpoch       = "epoch"    // 秒, 带小数部分
+	TimeEpochMillis = "epoch_ms" // 毫秒
+	TimeEpochNanos  = "epoch_ns" // 纳秒
+)
+
+// formatOptions storage format parameters
+type formatOptions struct {
+	timeFormat   string         // 记录时间的格式
+	timeLocation *time.Location // 记录时间转换到的时区, nil 表示不转换
+}
+
+// FormatOptions configures TerminalFormat, LogfmtFormat and JsonFormatEx.
+type FormatOptions func(*formatOptions)
+
+// SetTimeFormat sets how the time of records is written: a layout for
+// time.Format, such as time.RFC3339Nano, or one of TimeEpoch,
+// TimeEpochMillis and TimeEpochNanos.
+func SetTimeFormat(layout string) FormatOptions {
+	return func(o *formatOptions) {
+		o.timeFormat = layout
+	}
+}
+
+// SetTimeLocation converts the time of records to loc before writing it,
+// so SetTimeLocation(time.UTC) writes every record in UTC.
+func SetTimeLocation(loc *time.Location) FormatOptions {
+	return func(o *formatOptions) {
+		o.timeLocation = loc
+	}
+}
+
+func newFormatOptions(layout string, opts []FormatOptions) formatOptions {
+	o := formatOptions{timeFormat: layout}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	return o
+}
+
+// epochTime reports whether times are written as numbers.
+func (o *formatOptions) epochTime() bool {
+	switch o.timeFormat {
+	case TimeEpoch, TimeEpochMillis, TimeEpochNanos:
+		return true
+	}
+	return false
+}
+
+// appendTime appends t, as the options say, to b.
+func (o *formatOptions) appendTime(b []byte, t time.Time) []byte {
+	if o.timeLocation != nil {
+		t = t.In(o.timeLocation)
+	}
+	switch o.timeFormat {
+	case TimeEpoch:
+		sec, nsec := t.Unix(), int64(t.Nanosecond())
+		if sec < 0 && nsec > 0 {
+			// Unix rounds down, so -0.25s is -1s plus 750ms
+			sec, nsec = sec+1, 1e9-nsec
+			if sec == 0 {
+				b = append(b, '-')
+			}
+		}
+		b = strconv.AppendInt(b, sec, 10)
+		if nsec == 0 {
+			return b
+		}
+		var frac [10]byte
+		frac[0] = '.'
+		for i := 9; i > 0; i-- {
+			frac[i] = byte('0' + nsec%10)
+			nsec /= 10
+		}
+		n := len(frac)
+		for frac[n-1] == '0' {
+			n--
+		}
+		return append(b, frac[:n]...)
+	case TimeEpochMillis:
+		return strconv.AppendInt(b, t.UnixNano()/int64(time.Millisecond), 10)
+	case TimeEpochNanos:
+		return strconv.AppendInt(b, t.UnixNano(), 10)
+	}
+	return t.AppendFormat(b, o.timeFormat)
+}
@@ -0,0 +1,51 @@
+package log
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeFormat(t *testing.T) {
+	t.Parallel()
+
+	at := time.Date(2026, 10, 17, 21, 4, 5, 120000000, time.FixedZone("CST", 8*3600))
+	names := RecordKeyNames{Order: []RecordKey{KeyTime, KeyMsg}}
+	for _, tt := range []struct {
+		fmtr Format
+		want string
+	}{
+		{JsonFormat(), `{"time":"2026-10-17T21:04:05.12+08:00","msg":"hi"}`},
+		{JsonFormat(SetTimeLocation(time.UTC)), `{"time":"2026-10-17T13:04:05.12Z","msg":"hi"}`},
+		{JsonFormat(SetTimeFormat(TimeEpoch)), `{"time":1792242245.12,"msg":"hi"}`},
+		{JsonFormat(SetTimeFormat(TimeEpochMillis)), `{"time":1792242245120,"msg":"hi"}`},
+		{JsonFormat(SetTimeFormat(TimeEpochNanos)), `{"time":1792242245120000000,"msg":"hi"}`},
+		{JsonFormat(SetTimeFormat(`"2006"`)), `{"time":"\"2026\"","msg":"hi"}`},
+		{LogfmtFormat(), `time="2026-10-17 21:04:05.12" msg=hi`},
+		{LogfmtFormat(SetTimeFormat(time.Kitchen), SetTimeLocation(time.UTC)), `time=1:04PM msg=hi`},
+		{TerminalFormat(SetTimeFormat(TimeEpochMillis)), `[1792242245120] msg=hi`},
+	} {
+		l, buf := testFormatter(KeyNamesFormat(names, tt.fmtr))
+		l.SetClock(func() time.Time { return at })
+		l.New().Info("hi")
+		if got := buf.String(); got != tt.want+"\n" && got != tt.want+" \n" {
+			t.Errorf("got  %q\nwant %q", got, tt.want)
+		}
+	}
+}
+
+func TestTimeEpoch(t *testing.T) {
+	o := newFormatOptions(TimeEpoch, nil)
+	for _, tt := range []struct {
+		t    time.Time
+		want string
+	}{
+		{time.Unix(5, 0), "5"},
+		{time.Unix(5, 1), "5.000000001"},
+		{time.Unix(-1, 750000000), "-0.25"},
+		{time.Unix(-2, 500000000), "-1.5"},
+	} {
+		if got := string(o.appendTime(nil, tt.t)); got != tt.want {
+			t.Errorf("%v: got %s, want %s", tt.t, got, tt.want)
+		}
+	}
+}
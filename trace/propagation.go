@@ -0,0 +1,410 @@
+package trace
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	mrand "math/rand"
+	"net/http"
+	"strings"
+)
+
+// Headers of W3C Trace Context, https://www.w3.org/TR/trace-context/,
+// and of Zipkin B3, https://github.com/openzipkin/b3-propagation.
+const (
+	TraceparentHeader    = "traceparent"
+	TracestateHeader     = "tracestate"
+	B3Header             = "b3"
+	B3TraceIDHeader      = "X-B3-TraceId"
+	B3SpanIDHeader       = "X-B3-SpanId"
+	B3ParentSpanIDHeader = "X-B3-ParentSpanId"
+	B3SampledHeader      = "X-B3-Sampled"
+	B3FlagsHeader        = "X-B3-Flags"
+)
+
+// Trace flags of a Span.
+const (
+	// FlagSampled means the caller may have recorded the trace.
+	FlagSampled byte = 0x01
+	// FlagDebug is B3's debug sampling decision. It isn't a W3C flag and
+	// isn't written into traceparent.
+	FlagDebug byte = 0x80
+)
+
+// maxTracestateMembers is the most list members a tracestate may have.
+const maxTracestateMembers = 32
+
+// NewTraceID returns a random 16 byte trace id in lowercase hex.
+func NewTraceID() string {
+	return newID(16)
+}
+
+// NewSpanID returns a random 8 byte span id in lowercase hex.
+func NewSpanID() string {
+	return newID(8)
+}
+
+func newID(n int) string {
+	b := make([]byte, n)
+	for {
+		if _, err := rand.Read(b); err != nil {
+			mrand.Read(b)
+		}
+		// all zero ids are invalid
+		for _, c := range b {
+			if c != 0 {
+				return hex.EncodeToString(b)
+			}
+		}
+	}
+}
+
+// NewSpan returns the sampled root span of a new trace.
+func NewSpan() Span {
+	return Span{TraceID: NewTraceID(), SpanID: NewSpanID(), Flags: FlagSampled}
+}
+
+// Child returns a new span of s's trace whose parent is s, or a new
+// trace if s has none. A server starts the span of a request as the child
+// of the span its headers name:
+//
+//     span, _ := trace.SpanFromHeader(req.Header)
+//     ctx := trace.NewSpanContext(req.Context(), span.Child())
+//
+func (s Span) Child() Span {
+	if s.TraceID == "" {
+		return NewSpan()
+	}
+	return Span{TraceID: s.TraceID, SpanID: NewSpanID(), ParentID: s.SpanID, Flags: s.Flags, State: s.State}
+}
+
+// Sampled reports whether s has FlagSampled.
+func (s Span) Sampled() bool {
+	return s.Flags&FlagSampled != 0
+}
+
+// ParseTraceparent parses a W3C traceparent header value such as
+//
+//     00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+//
+// into a Span with its trace id, its span id, the caller's, and flags.
+// Versions after 00 are parsed as far as version 00 goes.
+func ParseTraceparent(s string) (Span, error) {
+	s = strings.Trim(s, " \t")
+	if len(s) < 55 || s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return Span{}, fmt.Errorf("trace: malformed traceparent %q", s)
+	}
+	version, traceID, spanID, flags := s[:2], s[3:35], s[36:52], s[53:55]
+	switch {
+	case !isHex(version) || version == "ff":
+		return Span{}, fmt.Errorf("trace: bad traceparent version %q", version)
+	case version == "00" && len(s) != 55, len(s) > 55 && s[55] != '-':
+		return Span{}, fmt.Errorf("trace: malformed traceparent %q", s)
+	case !isID(traceID):
+		return Span{}, fmt.Errorf("trace: bad trace id %q", traceID)
+	case !isID(spanID):
+		return Span{}, fmt.Errorf("trace: bad span id %q", spanID)
+	case !isHex(flags):
+		return Span{}, fmt.Errorf("trace: bad trace flags %q", flags)
+	}
+	var f [1]byte
+	hex.Decode(f[:], []byte(flags))
+	return Span{TraceID: traceID, SpanID: spanID, Flags: f[0] &^ FlagDebug}, nil
+}
+
+// Traceparent returns the W3C traceparent header value of s, or "" if s
+// has no valid trace and span ids. A 16 digit B3 trace id is padded with
+// zeros.
+func (s Span) Traceparent() string {
+	traceID := s.TraceID
+	if len(traceID) == 16 {
+		traceID = "0000000000000000" + traceID
+	}
+	if len(traceID) != 32 || !isID(traceID) || len(s.SpanID) != 16 || !isID(s.SpanID) {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-%02x", traceID, s.SpanID, s.Flags&^FlagDebug)
+}
+
+// ParseTracestate checks a W3C tracestate header value, or the values of
+// several tracestate headers joined with commas, and returns it with
+// empty list members and optional white space removed.
+func ParseTracestate(s string) (string, error) {
+	var members []string
+	seen := make(map[string]bool)
+	for _, m := range strings.Split(s, ",") {
+		if m = strings.Trim(m, " \t"); m == "" {
+			continue
+		}
+		i := strings.IndexByte(m, '=')
+		if i < 0 {
+			return "", fmt.Errorf("trace: tracestate member %q has no value", m)
+		}
+		key, value := m[:i], m[i+1:]
+		if !isTracestateKey(key) {
+			return "", fmt.Errorf("trace: bad tracestate key %q", key)
+		}
+		if !isTracestateValue(value) {
+			return "", fmt.Errorf("trace: bad tracestate value %q", value)
+		}
+		if seen[key] {
+			return "", fmt.Errorf("trace: tracestate key %q repeated", key)
+		}
+		seen[key] = true
+		members = append(members, m)
+	}
+	if len(members) > maxTracestateMembers {
+		return "", fmt.Errorf("trace: tracestate has %d members, more than %d", len(members), maxTracestateMembers)
+	}
+	return strings.Join(members, ","), nil
+}
+
+// ParseB3 parses a single b3 header value,
+//
+//     {TraceId}-{SpanId}-{SamplingState}-{ParentSpanId}
+//
+// where the last two are optional. A value holding only a sampling
+// decision is reported as an error, since it names no span.
+func ParseB3(s string) (Span, error) {
+	parts := strings.Split(strings.ToLower(strings.Trim(s, " \t")), "-")
+	if len(parts) < 2 || len(parts) > 4 {
+		return Span{}, fmt.Errorf("trace: malformed b3 %q", s)
+	}
+	span := Span{TraceID: parts[0], SpanID: parts[1]}
+	if !isB3TraceID(span.TraceID) {
+		return Span{}, fmt.Errorf("trace: bad trace id %q", span.TraceID)
+	}
+	if len(span.SpanID) != 16 || !isID(span.SpanID) {
+		return Span{}, fmt.Errorf("trace: bad span id %q", span.SpanID)
+	}
+	if len(parts) > 2 {
+		switch parts[2] {
+		case "1":
+			span.Flags = FlagSampled
+		case "0":
+		case "d":
+			span.Flags = FlagSampled | FlagDebug
+		default:
+			return Span{}, fmt.Errorf("trace: bad b3 sampling state %q", parts[2])
+		}
+	}
+	if len(parts) > 3 {
+		if span.ParentID = parts[3]; len(span.ParentID) != 16 || !isID(span.ParentID) {
+			return Span{}, fmt.Errorf("trace: bad parent span id %q", span.ParentID)
+		}
+	}
+	return span, nil
+}
+
+// B3 returns the single b3 header value of s, or "" if s has no valid
+// trace and span ids.
+func (s Span) B3() string {
+	if !isB3TraceID(s.TraceID) || len(s.SpanID) != 16 || !isID(s.SpanID) {
+		return ""
+	}
+	b3 := s.TraceID + "-" + s.SpanID + "-" + s.b3Sampling()
+	if len(s.ParentID) == 16 && isID(s.ParentID) {
+		b3 += "-" + s.ParentID
+	}
+	return b3
+}
+
+func (s Span) b3Sampling() string {
+	switch {
+	case s.Flags&FlagDebug != 0:
+		return "d"
+	case s.Sampled():
+		return "1"
+	}
+	return "0"
+}
+
+// ParseB3Headers parses the X-B3-* headers.
+func ParseB3Headers(header http.Header) (Span, error) {
+	span := Span{
+		TraceID:  strings.ToLower(header.Get(B3TraceIDHeader)),
+		SpanID:   strings.ToLower(header.Get(B3SpanIDHeader)),
+		ParentID: strings.ToLower(header.Get(B3ParentSpanIDHeader)),
+	}
+	if !isB3TraceID(span.TraceID) {
+		return Span{}, fmt.Errorf("trace: bad trace id %q", span.TraceID)
+	}
+	if len(span.SpanID) != 16 || !isID(span.SpanID) {
+		return Span{}, fmt.Errorf("trace: bad span id %q", span.SpanID)
+	}
+	if span.ParentID != "" && (len(span.ParentID) != 16 || !isID(span.ParentID)) {
+		return Span{}, fmt.Errorf("trace: bad parent span id %q", span.ParentID)
+	}
+	switch sampled := header.Get(B3SampledHeader); sampled {
+	case "1", "true":
+		span.Flags = FlagSampled
+	case "", "0", "false":
+	default:
+		return Span{}, fmt.Errorf("trace: bad %s %q", B3SampledHeader, sampled)
+	}
+	if header.Get(B3FlagsHeader) == "1" {
+		span.Flags = FlagSampled | FlagDebug
+	}
+	return span, nil
+}
+
+// SpanFromHeader returns the span named by header, looking in turn at
+// traceparent and tracestate, b3, the X-B3-* headers and, for a trace id
+// only, TraceIDHeaderKey. Headers which don't parse are skipped.
+func SpanFromHeader(header http.Header) (span Span, ok bool) {
+	if header == nil {
+		return Span{}, false
+	}
+	if tp := header.Get(TraceparentHeader); tp != "" {
+		if span, err := ParseTraceparent(tp); err == nil {
+			// an invalid tracestate doesn't void the traceparent
+			span.State, _ = ParseTracestate(strings.Join(header.Values(TracestateHeader), ","))
+			return span, true
+		}
+	}
+	if b3 := header.Get(B3Header); b3 != "" {
+		if span, err := ParseB3(b3); err == nil {
+			return span, true
+		}
+	}
+	if header.Get(B3TraceIDHeader) != "" {
+		if span, err := ParseB3Headers(header); err == nil {
+			return span, true
+		}
+	}
+	if traceId := header.Get(TraceIDHeaderKey); traceId != "" {
+		return Span{TraceID: traceId}, true
+	}
+	return Span{}, false
+}
+
+// SpanFromRequest returns the span stored in req's context, or else the
+// one its headers name.
+func SpanFromRequest(req *http.Request) (span Span, ok bool) {
+	if span, ok = SpanFromContext(req.Context()); ok {
+		return span, true
+	}
+	return SpanFromHeader(req.Header)
+}
+
+// A Propagation is a way of passing a span on in HTTP headers.
+type Propagation int
+
+const (
+	PropagateW3C       Propagation = iota // traceparent and tracestate
+	PropagateB3                           // the single b3 header
+	PropagateB3Multi                      // the X-B3-* headers
+	PropagateRequestID                    // the trace id in TraceIDHeaderKey
+)
+
+// InjectHeader sets the headers which pass span on to the next service
+// as each of props says, W3C Trace Context if there are none. Usually
+// span is the one the outgoing request is made in, and the next service
+// starts its own as its child. Headers whose ids span hasn't are left
+// unset.
+func InjectHeader(header http.Header, span Span, props ...Propagation) {
+	if len(props) == 0 {
+		props = []Propagation{PropagateW3C}
+	}
+	for _, p := range props {
+		switch p {
+		case PropagateW3C:
+			if tp := span.Traceparent(); tp != "" {
+				header.Set(TraceparentHeader, tp)
+				if span.State != "" {
+					header.Set(TracestateHeader, span.State)
+				} else {
+					header.Del(TracestateHeader)
+				}
+			}
+		case PropagateB3:
+			if b3 := span.B3(); b3 != "" {
+				header.Set(B3Header, b3)
+			}
+		case PropagateB3Multi:
+			if span.B3() == "" {
+				continue
+			}
+			header.Set(B3TraceIDHeader, span.TraceID)
+			header.Set(B3SpanIDHeader, span.SpanID)
+			if span.ParentID != "" {
+				header.Set(B3ParentSpanIDHeader, span.ParentID)
+			} else {
+				header.Del(B3ParentSpanIDHeader)
+			}
+			if span.Flags&FlagDebug != 0 {
+				header.Set(B3FlagsHeader, "1")
+				header.Del(B3SampledHeader)
+			} else {
+				header.Set(B3SampledHeader, span.b3Sampling())
+				header.Del(B3FlagsHeader)
+			}
+		case PropagateRequestID:
+			if span.TraceID != "" {
+				header.Set(TraceIDHeaderKey, span.TraceID)
+			}
+		}
+	}
+}
+
+// isHex reports whether s is lowercase hex.
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// isID reports whether s is a lowercase hex id which isn't all zeros.
+func isID(s string) bool {
+	return isHex(s) && strings.Trim(s, "0") != ""
+}
+
+func isB3TraceID(s string) bool {
+	return (len(s) == 16 || len(s) == 32) && isID(s)
+}
+
+// isTracestateKey reports whether key is a simple-key or a
+// tenant@system multi-tenant key.
+func isTracestateKey(key string) bool {
+	if i := strings.IndexByte(key, '@'); i >= 0 {
+		tenant, system := key[:i], key[i+1:]
+		return len(tenant) <= 241 && len(system) <= 14 && tenant != "" && system != "" &&
+			isTracestateKeyChars(tenant, true) && isTracestateKeyChars(system, false)
+	}
+	return len(key) <= 256 && key != "" && isTracestateKeyChars(key, false)
+}
+
+func isTracestateKeyChars(s string, digitFirst bool) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z':
+		case '0' <= c && c <= '9':
+			if i == 0 && !digitFirst {
+				return false
+			}
+		case c == '_' || c == '-' || c == '*' || c == '/':
+			if i == 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func isTracestateValue(v string) bool {
+	if v == "" || len(v) > 256 || v[len(v)-1] == ' ' {
+		return false
+	}
+	for i := 0; i < len(v); i++ {
+		if c := v[i]; c < 0x20 || c > 0x7e || c == ',' || c == '=' {
+			return false
+		}
+	}
+	return true
+}
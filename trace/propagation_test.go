@@ -0,0 +1,161 @@
+package trace
+
+import (
+	"net/http"
+	"testing"
+)
+
+const (
+	testTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testSpanID  = "00f067aa0ba902b7"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	span, err := ParseTraceparent("00-" + testTraceID + "-" + testSpanID + "-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Span{TraceID: testTraceID, SpanID: testSpanID, Flags: FlagSampled}); span != want {
+		t.Errorf("have:%+v, want:%+v", span, want)
+	}
+	if tp := span.Traceparent(); tp != "00-"+testTraceID+"-"+testSpanID+"-01" {
+		t.Errorf("have:%s", tp)
+	}
+
+	// later versions may append fields
+	if _, err := ParseTraceparent("01-" + testTraceID + "-" + testSpanID + "-00-xyz"); err != nil {
+		t.Error(err)
+	}
+	for _, tp := range []string{
+		"",
+		"00-" + testTraceID + "-" + testSpanID + "-01-xyz",
+		"ff-" + testTraceID + "-" + testSpanID + "-01",
+		"00-00000000000000000000000000000000-" + testSpanID + "-01",
+		"00-" + testTraceID + "-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-" + testSpanID + "-01",
+		"00-" + testTraceID + "-" + testSpanID + "-0g",
+		"01-" + testTraceID + "-" + testSpanID + "-01x",
+	} {
+		if _, err := ParseTraceparent(tp); err == nil {
+			t.Errorf("%q: no error", tp)
+		}
+	}
+}
+
+func TestParseTracestate(t *testing.T) {
+	state, err := ParseTracestate(" rojo=00f067aa0ba902b7 ,, congo=t61rcWkgMzE,tenant@vendor=x y")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "rojo=00f067aa0ba902b7,congo=t61rcWkgMzE,tenant@vendor=x y"; state != want {
+		t.Errorf("have:%q, want:%q", state, want)
+	}
+	for _, s := range []string{"a", "A=1", "a=1,a=2", "a=b=c", "a=", "1a=1", "a@=1"} {
+		if _, err := ParseTracestate(s); err == nil {
+			t.Errorf("%q: no error", s)
+		}
+	}
+}
+
+func TestParseB3(t *testing.T) {
+	for _, tt := range []struct {
+		b3   string
+		want Span
+	}{
+		{testTraceID + "-" + testSpanID, Span{TraceID: testTraceID, SpanID: testSpanID}},
+		{"a3ce929d0e0e4736-" + testSpanID + "-1", Span{TraceID: "a3ce929d0e0e4736", SpanID: testSpanID, Flags: FlagSampled}},
+		{testTraceID + "-" + testSpanID + "-d-05e3ac9a4f6e3b90",
+			Span{TraceID: testTraceID, SpanID: testSpanID, ParentID: "05e3ac9a4f6e3b90", Flags: FlagSampled | FlagDebug}},
+	} {
+		span, err := ParseB3(tt.b3)
+		if err != nil {
+			t.Errorf("%q: %v", tt.b3, err)
+			continue
+		}
+		if span != tt.want {
+			t.Errorf("%q: have:%+v, want:%+v", tt.b3, span, tt.want)
+		}
+	}
+	for _, b3 := range []string{"0", "1", testTraceID, testTraceID + "-" + testSpanID + "-x", "abc-" + testSpanID} {
+		if _, err := ParseB3(b3); err == nil {
+			t.Errorf("%q: no error", b3)
+		}
+	}
+}
+
+func TestSpanFromHeader(t *testing.T) {
+	header := make(http.Header)
+	header.Set(TraceparentHeader, "00-"+testTraceID+"-"+testSpanID+"-01")
+	header.Add(TracestateHeader, "a=1")
+	header.Add(TracestateHeader, "b=2")
+	header.Set(B3Header, "a3ce929d0e0e4736-"+testSpanID)
+	span, ok := SpanFromHeader(header)
+	if want := (Span{TraceID: testTraceID, SpanID: testSpanID, Flags: FlagSampled, State: "a=1,b=2"}); !ok || span != want {
+		t.Errorf("have:(%+v, %t), want:%+v", span, ok, want)
+	}
+
+	// a bad traceparent falls back to b3
+	header.Set(TraceparentHeader, "bad")
+	if span, ok := SpanFromHeader(header); !ok || span.TraceID != "a3ce929d0e0e4736" {
+		t.Errorf("have:(%+v, %t)", span, ok)
+	}
+
+	header = make(http.Header)
+	header.Set(B3TraceIDHeader, testTraceID)
+	header.Set(B3SpanIDHeader, testSpanID)
+	header.Set(B3SampledHeader, "1")
+	if span, ok := SpanFromHeader(header); !ok || span != (Span{TraceID: testTraceID, SpanID: testSpanID, Flags: FlagSampled}) {
+		t.Errorf("have:(%+v, %t)", span, ok)
+	}
+	if id, ok := FromHeader(header); !ok || id != testTraceID {
+		t.Errorf("have:(%s, %t)", id, ok)
+	}
+}
+
+func TestInjectHeader(t *testing.T) {
+	parent := Span{TraceID: testTraceID, SpanID: testSpanID, Flags: FlagSampled, State: "a=1"}
+	span := parent.Child()
+	if span.TraceID != testTraceID || span.ParentID != testSpanID || !isID(span.SpanID) || len(span.SpanID) != 16 ||
+		span.SpanID == testSpanID || span.State != "a=1" || !span.Sampled() {
+		t.Fatalf("unexpected child %+v", span)
+	}
+
+	header := make(http.Header)
+	InjectHeader(header, span, PropagateW3C, PropagateB3, PropagateB3Multi, PropagateRequestID)
+	for _, p := range []func(http.Header) (Span, error){
+		func(h http.Header) (Span, error) {
+			span, err := ParseTraceparent(h.Get(TraceparentHeader))
+			span.ParentID, span.State = testSpanID, h.Get(TracestateHeader)
+			return span, err
+		},
+		func(h http.Header) (Span, error) {
+			span, err := ParseB3(h.Get(B3Header))
+			span.State = "a=1"
+			return span, err
+		},
+		func(h http.Header) (Span, error) {
+			span, err := ParseB3Headers(h)
+			span.State = "a=1"
+			return span, err
+		},
+	} {
+		if got, err := p(header); err != nil || got != span {
+			t.Errorf("have:(%+v, %v), want:%+v", got, err, span)
+		}
+	}
+	if header.Get(TraceIDHeaderKey) != testTraceID {
+		t.Errorf("have request id %q", header.Get(TraceIDHeaderKey))
+	}
+
+	// nothing to inject
+	header = make(http.Header)
+	InjectHeader(header, Span{})
+	if len(header) != 0 {
+		t.Errorf("have:%v", header)
+	}
+
+	root := NewSpan()
+	if len(root.TraceID) != 32 || root.ParentID != "" || root.Traceparent() == "" {
+		t.Errorf("unexpected root %+v", root)
+	}
+}
@@ -9,6 +9,34 @@ type Tracer interface {
 	TraceId() string
 }
 
+// SpanTracer is a Tracer which also knows the span it is in and that
+// span's parent.
+type SpanTracer interface {
+	Tracer
+	SpanId() string
+	ParentId() string
+}
+
+// Span identifies a span: the trace it belongs to, itself and the span
+// it was started from. Any of the ids may be empty.
+type Span struct {
+	TraceID  string
+	SpanID   string
+	ParentID string
+	// Flags are the trace flags of W3C Trace Context, see FlagSampled.
+	Flags byte
+	// State is the W3C tracestate of the trace, passed on unchanged.
+	State string
+}
+
+func (s Span) TraceId() string  { return s.TraceID }
+func (s Span) SpanId() string   { return s.SpanID }
+func (s Span) ParentId() string { return s.ParentID }
+
+type spanContextKey struct{}
+
+var _spanContextKey spanContextKey
+
 type traceIdContextKey struct{}
 
 var _traceIdContextKey traceIdContextKey
@@ -34,6 +62,34 @@ func FromContext(ctx context.Context) (traceId string, ok bool) {
 	return
 }
 
+// NewSpanContext returns a copy of ctx carrying span, whose trace id
+// FromContext returns too.
+func NewSpanContext(ctx context.Context, span Span) context.Context {
+	if span == (Span{}) {
+		return ctx
+	}
+	ctx = NewContext(ctx, span.TraceID)
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, _spanContextKey, span)
+}
+
+// SpanFromContext returns the span stored in ctx by NewSpanContext, or
+// one holding only the trace id stored by NewContext.
+func SpanFromContext(ctx context.Context) (span Span, ok bool) {
+	if ctx == nil {
+		return Span{}, false
+	}
+	if span, ok = ctx.Value(_spanContextKey).(Span); ok {
+		return span, true
+	}
+	if traceId, ok := FromContext(ctx); ok {
+		return Span{TraceID: traceId}, true
+	}
+	return Span{}, false
+}
+
 func FromRequest(req *http.Request) (traceId string, ok bool) {
 	traceId, ok = FromContext(req.Context())
 	if ok {
@@ -44,10 +100,8 @@ func FromRequest(req *http.Request) (traceId string, ok bool) {
 
 const TraceIDHeaderKey = "request_id"
 
+// FromHeader returns the trace id of the span SpanFromHeader finds.
 func FromHeader(header http.Header) (traceId string, ok bool) {
-	traceId = header.Get(TraceIDHeaderKey)
-	if traceId != "" {
-		return traceId, true
-	}
-	return "", false
+	span, ok := SpanFromHeader(header)
+	return span.TraceID, ok
 }
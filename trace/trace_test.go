@@ -358,3 +358,31 @@ func TestFromHeader(t *testing.T) {
 		}
 	}
 }
+
+func TestSpanContext(t *testing.T) {
+	if _, ok := SpanFromContext(context.Background()); ok {
+		t.Error("found a span in an empty context")
+	}
+
+	ctx := NewContext(context.Background(), "t0")
+	if span, ok := SpanFromContext(ctx); !ok || span != (Span{TraceID: "t0"}) {
+		t.Errorf("have:(%+v, %t), want the trace id only", span, ok)
+	}
+
+	want := Span{TraceID: "t1", SpanID: "s1", ParentID: "p1"}
+	ctx = NewSpanContext(ctx, want)
+	if span, ok := SpanFromContext(ctx); !ok || span != want {
+		t.Errorf("have:(%+v, %t), want:(%+v, true)", span, ok, want)
+	}
+	if id, ok := FromContext(ctx); !ok || id != "t1" {
+		t.Errorf("have:(%s, %t), want:(t1, true)", id, ok)
+	}
+	if NewSpanContext(ctx, Span{}) != ctx {
+		t.Error("an empty span changed the context")
+	}
+
+	var tracer SpanTracer = want
+	if tracer.TraceId() != "t1" || tracer.SpanId() != "s1" || tracer.ParentId() != "p1" {
+		t.Errorf("unexpected ids of %+v", tracer)
+	}
+}
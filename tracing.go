@@ -0,0 +1,155 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/wuzuoliang/log/trace"
+)
+
+// A TraceExtractor returns the ids of the trace, span and parent span a
+// record was logged in, from the record's context, which is never nil.
+// The formats write the ids that aren't empty under the RequestID,
+// SpanID and ParentID key names.
+type TraceExtractor func(ctx context.Context) trace.Span
+
+var _traceExtractorPtr unsafe.Pointer = unsafe.Pointer(&_defaultTraceExtractor) // *TraceExtractor
+
+// _defaultTraceExtractor understands the contexts of the trace package,
+// a trace.Tracer given as the context, and the request_id key the
+// formats have always looked for.
+var _defaultTraceExtractor = TraceExtractors(
+	TraceFromContext(),
+	TraceFromTracer(nil),
+	TraceFromKeys(requestID, nil, nil),
+)
+
+// SetTraceExtractor replaces the function the formats get trace ids
+// with. Passing nil restores the default, which tries, in order,
+// TraceFromContext, the context itself as a trace.Tracer and a string
+// stored under the "request_id" key.
+func SetTraceExtractor(e TraceExtractor) {
+	if e == nil {
+		e = _defaultTraceExtractor
+	}
+	atomic.StorePointer(&_traceExtractorPtr, unsafe.Pointer(&e))
+}
+
+func getTraceExtractor() TraceExtractor {
+	return *(*TraceExtractor)(atomic.LoadPointer(&_traceExtractorPtr))
+}
+
+// TraceFromContext returns a TraceExtractor which reads the span stored
+// with trace.NewSpanContext, or the trace id stored with trace.NewContext.
+func TraceFromContext() TraceExtractor {
+	return func(ctx context.Context) trace.Span {
+		span, _ := trace.SpanFromContext(ctx)
+		return span
+	}
+}
+
+// TraceFromTracer returns a TraceExtractor which reads the ids of the
+// trace.Tracer stored in the context under key, or of the context itself
+// if key is nil. The span and parent ids are only known to a
+// trace.SpanTracer.
+func TraceFromTracer(key interface{}) TraceExtractor {
+	return func(ctx context.Context) trace.Span {
+		v := interface{}(ctx)
+		if key != nil {
+			v = ctx.Value(key)
+		}
+		tracer, ok := v.(trace.Tracer)
+		if !ok || nilPointer(tracer) {
+			return trace.Span{}
+		}
+		span := trace.Span{TraceID: tracer.TraceId()}
+		if st, ok := tracer.(trace.SpanTracer); ok {
+			span.SpanID = st.SpanId()
+			span.ParentID = st.ParentId()
+		}
+		return span
+	}
+}
+
+// TraceFromKeys returns a TraceExtractor which reads the ids stored in
+// the context under the given keys, as strings or fmt.Stringers. Nil keys
+// are skipped. For ids stored by another package:
+//
+//     log.SetTraceExtractor(log.TraceFromKeys("trace-id", "span-id", nil))
+//
+func TraceFromKeys(traceKey, spanKey, parentKey interface{}) TraceExtractor {
+	return func(ctx context.Context) trace.Span {
+		return trace.Span{
+			TraceID:  contextID(ctx, traceKey),
+			SpanID:   contextID(ctx, spanKey),
+			ParentID: contextID(ctx, parentKey),
+		}
+	}
+}
+
+// TraceExtractors returns a TraceExtractor which returns the ids of the
+// first of es which finds a trace id.
+func TraceExtractors(es ...TraceExtractor) TraceExtractor {
+	return func(ctx context.Context) trace.Span {
+		for _, e := range es {
+			if span := e(ctx); span.TraceID != "" {
+				return span
+			}
+		}
+		return trace.Span{}
+	}
+}
+
+// contextID returns the id stored in ctx under key.
+func contextID(ctx context.Context, key interface{}) string {
+	if key == nil {
+		return ""
+	}
+	switch v := ctx.Value(key).(type) {
+	case string:
+		return v
+	case fmt.Stringer:
+		if !nilPointer(v) {
+			return v.String()
+		}
+	}
+	return ""
+}
+
+// nilPointer reports whether v is a nil pointer, whose methods can't be
+// called safely.
+func nilPointer(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+// recordTrace returns the trace ids of r's context.
+func recordTrace(r *Record) trace.Span {
+	if r.Ctx == nil {
+		return trace.Span{}
+	}
+	return getTraceExtractor()(r.Ctx)
+}
+
+// traceKey reports whether key is one of the trace ids, which are only
+// written when the record has them.
+func traceKey(key RecordKey) bool {
+	return key == KeyRequestID || key == KeySpanID || key == KeyParentID
+}
+
+// traceID returns the id of span written under key, one of KeyRequestID,
+// KeySpanID and KeyParentID.
+func traceID(span trace.Span, key RecordKey) string {
+	switch key {
+	case KeyRequestID:
+		return span.TraceID
+	case KeySpanID:
+		return span.SpanID
+	case KeyParentID:
+		return span.ParentID
+	}
+	return ""
+}
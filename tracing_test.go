@@ -0,0 +1,76 @@
+package log
+
+import (
+	"context"
+	"testing"
+
+	"github.com/wuzuoliang/log/trace"
+)
+
+type ctxTracer struct {
+	context.Context
+	id string
+}
+
+func (t *ctxTracer) TraceId() string { return t.id }
+
+type stringerID string
+
+func (s *stringerID) String() string { return string(*s) }
+
+func TestTraceExtractor(t *testing.T) {
+	t.Parallel()
+
+	bg := context.Background()
+	var nilID *stringerID
+	span := trace.Span{TraceID: "t1", SpanID: "s1", ParentID: "p1"}
+	for _, tt := range []struct {
+		name string
+		e    TraceExtractor
+		ctx  context.Context
+		want trace.Span
+	}{
+		{"empty", _defaultTraceExtractor, bg, trace.Span{}},
+		{"trace id", _defaultTraceExtractor, trace.NewContext(bg, "t0"), trace.Span{TraceID: "t0"}},
+		{"span", _defaultTraceExtractor, trace.NewSpanContext(bg, span), span},
+		{"context tracer", _defaultTraceExtractor, &ctxTracer{bg, "t2"}, trace.Span{TraceID: "t2"}},
+		{"raw key", _defaultTraceExtractor, context.WithValue(bg, "request_id", "t3"), trace.Span{TraceID: "t3"}},
+		{"raw key not a string", _defaultTraceExtractor, context.WithValue(bg, "request_id", 3), trace.Span{}},
+		{"tracer key", TraceFromTracer("tracer"), context.WithValue(bg, "tracer", span), span},
+		{"nil tracer", TraceFromTracer("tracer"), context.WithValue(bg, "tracer", (*ctxTracer)(nil)), trace.Span{}},
+		{"stringer keys", TraceFromKeys("t", "s", "p"),
+			context.WithValue(context.WithValue(bg, "t", "t4"), "s", nilID), trace.Span{TraceID: "t4"}},
+	} {
+		if got := tt.e(tt.ctx); got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTraceFields(t *testing.T) {
+	ctx := trace.NewSpanContext(context.Background(), trace.Span{TraceID: "t1", SpanID: "s1", ParentID: "p1"})
+	for _, tt := range []struct {
+		fmtr Format
+		want string
+	}{
+		{LogfmtFormat(), `msg=hi request_id=t1 span_id=s1 parent_id=p1 a=1` + "\n"},
+		{JsonFormat(), `{"msg":"hi","request_id":"t1","span_id":"s1","parent_id":"p1","a":1}` + "\n"},
+	} {
+		l, buf := testFormatter(KeyNamesFormat(RecordKeyNames{
+			Order: []RecordKey{KeyMsg, KeyRequestID, KeySpanID, KeyParentID},
+		}, tt.fmtr))
+		l.InfoContext(ctx, "hi", "a", 1)
+		if got := buf.String(); got != tt.want {
+			t.Errorf("got  %s\nwant %s", got, tt.want)
+		}
+	}
+
+	SetTraceExtractor(TraceFromKeys("tid", nil, nil))
+	defer SetTraceExtractor(nil)
+	l, buf := testFormatter(KeyNamesFormat(RecordKeyNames{Order: []RecordKey{KeyMsg, KeyRequestID, KeySpanID}}, LogfmtFormat()))
+	l.InfoContext(ctx, "hi")
+	l.InfoContext(context.WithValue(ctx, "tid", "t9"), "hi")
+	if want := "msg=hi\nmsg=hi request_id=t9\n"; buf.String() != want {
+		t.Errorf("got  %s\nwant %s", buf.String(), want)
+	}
+}